@@ -5,37 +5,66 @@ import (
 	"wintersc/graph"
 )
 
-func BellmanFord(g *graph.Graph, start int) (map[int]int, map[int]int, bool) {
+// Кратчайшие пути от start. View не хранит направление рёбер, поэтому граф
+// неориентированный, как и в остальных алгоритмах пакета, а не ориентированный
+// по порядку U → V в списке Graph.Edge. Каждое ребро можно пройти в обе
+// стороны, поэтому ребро отрицательного веса, достижимое из start, само
+// образует отрицательный цикл u → v → u, а отрицательная петля — цикл
+// из одного ребра. Других отрицательных циклов в неориентированном графе
+// не бывает. Поэтому:
+//   - если из start достижимо ребро отрицательного веса, функция возвращает
+//     negativeCycle = true и не вычисляет расстояний (в dist только start = 0);
+//   - иначе все достижимые веса неотрицательны, и результат совпадает
+//     с Dijkstra; отрицательные рёбра вне компоненты start не мешают.
+func BellmanFord(g graph.View, start int) (map[int]int, map[int]int, bool) {
 	// Инициализация расстояний и предков
 	dist := make(map[int]int)
 	prev := make(map[int]int)
 
 	// Устанавливаем все расстояния как "бесконечность"
-	for vertex := range g.Adj {
+	for vertex := range g.Vertices() {
 		dist[vertex] = math.MaxInt32
 		prev[vertex] = -1
 	}
 	dist[start] = 0
 
-	// Основной цикл алгоритма (проходим |V| - 1 раз)
-	for i := 1; i < len(g.Adj); i++ {
-		for _, edge := range g.Edge {
-			// Если найден более короткий путь через ребро
-			if dist[edge.U] != math.MaxInt32 && dist[edge.U]+edge.W < dist[edge.V] {
-				dist[edge.V] = dist[edge.U] + edge.W
-				prev[edge.V] = edge.U
+	// Ищем отрицательные рёбра, включая петли, в компоненте start
+	reached := map[int]bool{start: true}
+	queue := []int{start}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for v, w := range g.Neighbors(u) {
+			if w < 0 {
+				return dist, prev, true
+			}
+			if !reached[v] {
+				reached[v] = true
+				queue = append(queue, v)
 			}
 		}
 	}
 
-	// Проверка на наличие отрицательных циклов
-	negativeCycle := false
-	for _, edge := range g.Edge {
-		if dist[edge.U] != math.MaxInt32 && dist[edge.U]+edge.W < dist[edge.V] {
-			negativeCycle = true
+	// Основной цикл алгоритма (проходим |V| - 1 раз).
+	// Neighbors перечисляет ребро с обоих концов, то есть в обе стороны.
+	for i := 1; i < g.NumVertices(); i++ {
+		changed := false
+		for u := range g.Vertices() {
+			if dist[u] == math.MaxInt32 {
+				continue
+			}
+			for v, w := range g.Neighbors(u) {
+				if dist[u]+w < dist[v] {
+					dist[v] = dist[u] + w
+					prev[v] = u
+					changed = true
+				}
+			}
+		}
+		if !changed {
 			break
 		}
 	}
 
-	return dist, prev, negativeCycle
+	return dist, prev, false
 }
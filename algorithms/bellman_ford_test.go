@@ -0,0 +1,55 @@
+package algorithms
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+func TestBellmanFordMatchesDijkstra(t *testing.T) {
+	g := dijkstraGraph()
+	// Отрицательное ребро в другой компоненте не мешает
+	g.AddEdge(7, 8, -4)
+	dist, prev, negativeCycle := BellmanFord(g, 1)
+	if negativeCycle {
+		t.Fatal("найден отрицательный цикл, хотя из 1 отрицательных рёбер не достичь")
+	}
+	wantDist, wantPrev := Dijkstra(g, 1)
+	if !reflect.DeepEqual(dist, wantDist) {
+		t.Errorf("Dist %v, ожидалось %v", dist, wantDist)
+	}
+	for v, p := range wantPrev {
+		if prev[v] != p {
+			t.Errorf("Prev[%d] = %d, ожидалось %d", v, prev[v], p)
+		}
+	}
+}
+
+// Ребро отрицательного веса в неориентированном графе — отрицательный цикл
+func TestBellmanFordNegativeEdge(t *testing.T) {
+	tests := map[string]func(g *graph.Graph){
+		"ребро":  func(g *graph.Graph) { g.AddEdge(4, 5, -1) },
+		"петля":  func(g *graph.Graph) { g.AddEdge(3, 3, -1) },
+		"далеко": func(g *graph.Graph) { g.AddEdge(5, 9, -100) },
+	}
+	for name, add := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := graph.NewGraph()
+			g.AddEdge(1, 2, 1)
+			g.AddEdge(2, 3, 1)
+			g.AddEdge(3, 4, 1)
+			g.AddEdge(4, 5, 1)
+			add(g)
+			dist, _, negativeCycle := BellmanFord(g, 1)
+			if !negativeCycle {
+				t.Fatal("отрицательный цикл не найден")
+			}
+			for v, d := range dist {
+				if v != 1 && d != math.MaxInt32 {
+					t.Errorf("Dist[%d] = %d, расстояния не должны вычисляться", v, d)
+				}
+			}
+		})
+	}
+}
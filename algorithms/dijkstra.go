@@ -5,21 +5,74 @@ import (
 	"wintersc/graph"
 )
 
-func Dijkstra(g *graph.Graph, start int) (map[int]int, map[int]int) {
+// Параметры поиска кратчайших путей
+type DijkstraOptions struct {
+	// Не раскрывать вершины дальше Radius, если HasRadius. Radius = 0
+	// оставляет только сами источники (и вершины за рёбрами веса 0).
+	Radius    int
+	HasRadius bool
+	Targets   []int // Остановиться, когда все цели получили окончательное расстояние
+	// Вызывается при окончательной фиксации расстояния до вершины.
+	// Если вернуть false, поиск прекращается.
+	Visit func(vertex, dist int) bool
+}
+
+// Результат поиска: расстояния, предки и ближайший источник для каждой вершины.
+// В Dist только окончательные расстояния: при раннем выходе (Targets или
+// Visit) вершины, которые не успели зафиксировать, остаются с math.MaxInt32
+// и не попадают в Prev и Origin.
+type DijkstraResult struct {
+	Dist   map[int]int
+	Prev   map[int]int
+	Origin map[int]int
+}
+
+func Dijkstra(g graph.View, start int) (map[int]int, map[int]int) {
+	res := DijkstraFrom(g, []int{start}, DijkstraOptions{})
+	return res.Dist, res.Prev
+}
+
+// Расстояние до ближайшей вершины из набора sources
+func DijkstraMultiSource(g graph.View, sources []int) DijkstraResult {
+	return DijkstraFrom(g, sources, DijkstraOptions{})
+}
+
+// Все вершины, до которых можно добраться со стоимостью не больше radius
+func DijkstraWithinRadius(g graph.View, sources []int, radius int) DijkstraResult {
+	return DijkstraFrom(g, sources, DijkstraOptions{Radius: radius, HasRadius: true})
+}
+
+// Кратчайшие расстояния от sources до targets с ранним выходом
+func DijkstraToTargets(g graph.View, sources, targets []int) DijkstraResult {
+	return DijkstraFrom(g, sources, DijkstraOptions{Targets: targets})
+}
+
+// Общая реализация для всех вариантов алгоритма Дейкстры
+func DijkstraFrom(g graph.View, sources []int, opts DijkstraOptions) DijkstraResult {
 	// Создаём таблицу расстояний, изначально все - "бесконечность"
 	distances := make(map[int]int)
-	for _, edge := range g.Edge {
-		distances[edge.U] = math.MaxInt32
-		distances[edge.V] = math.MaxInt32
+	for vertex := range g.Vertices() {
+		distances[vertex] = math.MaxInt32
 	}
-	distances[start] = 0 // Начальная вершина
 
-	// Массив для восстановления пути
+	// Массив для восстановления пути и ближайший источник
 	prev := make(map[int]int)
+	origin := make(map[int]int)
 
-	// Создаём приоритетную очередь
+	// Создаём приоритетную очередь и кладём в неё все источники
 	pq := graph.NewPriorityQueue()
-	pq.Push(start, 0)
+	for _, s := range sources {
+		distances[s] = 0
+		origin[s] = s
+		pq.Push(s, 0)
+	}
+
+	remaining := make(map[int]bool)
+	for _, t := range opts.Targets {
+		remaining[t] = true
+	}
+	done := make(map[int]bool)
+	stopped := false
 
 	// Основной цикл алгоритма
 	for len(pq.Data) > 0 {
@@ -33,20 +86,49 @@ func Dijkstra(g *graph.Graph, start int) (map[int]int, map[int]int) {
 		currentDistance := current.Dist
 
 		// Если найденный путь длиннее, чем уже известный, пропускаем
-		if currentDistance > distances[currentNode] {
+		if currentDistance > distances[currentNode] || done[currentNode] {
 			continue
 		}
+		done[currentNode] = true
+
+		if opts.Visit != nil && !opts.Visit(currentNode, currentDistance) {
+			stopped = true
+			break
+		}
+		if len(opts.Targets) > 0 {
+			delete(remaining, currentNode)
+			if len(remaining) == 0 {
+				stopped = true
+				break
+			}
+		}
 
 		// Обновляем расстояния до соседей
-		for _, edge := range g.GetNeighbors(currentNode) {
-			newDistance := currentDistance + edge.W
-			if newDistance < distances[edge.V] {
-				distances[edge.V] = newDistance
-				prev[edge.V] = currentNode
-				pq.Push(edge.V, newDistance)
+		for neighbor, weight := range g.Neighbors(currentNode) {
+			newDistance := currentDistance + weight
+			if opts.HasRadius && newDistance > opts.Radius {
+				continue
+			}
+			if newDistance < distances[neighbor] {
+				distances[neighbor] = newDistance
+				prev[neighbor] = currentNode
+				origin[neighbor] = origin[currentNode]
+				pq.Push(neighbor, newDistance)
+			}
+		}
+	}
+
+	// Предварительные расстояния вершин на границе поиска ещё могут уменьшиться;
+	// нулевое (у источников) окончательно и так
+	if stopped {
+		for vertex, d := range distances {
+			if d != math.MaxInt32 && d > 0 && !done[vertex] {
+				distances[vertex] = math.MaxInt32
+				delete(prev, vertex)
+				delete(origin, vertex)
 			}
 		}
 	}
 
-	return distances, prev
+	return DijkstraResult{Dist: distances, Prev: prev, Origin: origin}
 }
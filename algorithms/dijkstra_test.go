@@ -0,0 +1,95 @@
+package algorithms
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// 1 —1— 2 —2— 3 —1— 4 —3— 5, короткое ребро 1 —5— 3 и изолированная вершина 6
+func dijkstraGraph() *graph.Graph {
+	g := graph.NewGraph()
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(2, 3, 2)
+	g.AddEdge(1, 3, 5)
+	g.AddEdge(3, 4, 1)
+	g.AddEdge(4, 5, 3)
+	g.AddVertex(6)
+	return g
+}
+
+const inf = math.MaxInt32
+
+func TestDijkstra(t *testing.T) {
+	dist, prev := Dijkstra(dijkstraGraph(), 1)
+	wantDist := map[int]int{1: 0, 2: 1, 3: 3, 4: 4, 5: 7, 6: inf}
+	wantPrev := map[int]int{2: 1, 3: 2, 4: 3, 5: 4}
+	if !reflect.DeepEqual(dist, wantDist) {
+		t.Errorf("Dist %v, ожидалось %v", dist, wantDist)
+	}
+	if !reflect.DeepEqual(prev, wantPrev) {
+		t.Errorf("Prev %v, ожидалось %v", prev, wantPrev)
+	}
+}
+
+func TestDijkstraMultiSource(t *testing.T) {
+	res := DijkstraMultiSource(dijkstraGraph(), []int{1, 5})
+	wantDist := map[int]int{1: 0, 2: 1, 3: 3, 4: 3, 5: 0, 6: inf}
+	wantOrigin := map[int]int{1: 1, 2: 1, 3: 1, 4: 5, 5: 5}
+	if !reflect.DeepEqual(res.Dist, wantDist) {
+		t.Errorf("Dist %v, ожидалось %v", res.Dist, wantDist)
+	}
+	if !reflect.DeepEqual(res.Origin, wantOrigin) {
+		t.Errorf("Origin %v, ожидалось %v", res.Origin, wantOrigin)
+	}
+}
+
+func TestDijkstraWithinRadius(t *testing.T) {
+	tests := []struct {
+		radius int
+		want   map[int]int
+	}{
+		{0, map[int]int{1: 0, 2: inf, 3: inf, 4: inf, 5: inf, 6: inf}},
+		{3, map[int]int{1: 0, 2: 1, 3: 3, 4: inf, 5: inf, 6: inf}},
+		{4, map[int]int{1: 0, 2: 1, 3: 3, 4: 4, 5: inf, 6: inf}},
+	}
+	for _, tt := range tests {
+		res := DijkstraWithinRadius(dijkstraGraph(), []int{1}, tt.radius)
+		if !reflect.DeepEqual(res.Dist, tt.want) {
+			t.Errorf("радиус %d: Dist %v, ожидалось %v", tt.radius, res.Dist, tt.want)
+		}
+	}
+}
+
+// После раннего выхода в результате только окончательные расстояния
+func TestDijkstraEarlyExit(t *testing.T) {
+	// Когда зафиксирована вершина 2, до 3 известен только путь длины 5 по ребру 1 — 3
+	res := DijkstraToTargets(dijkstraGraph(), []int{1}, []int{2})
+	want := map[int]int{1: 0, 2: 1, 3: inf, 4: inf, 5: inf, 6: inf}
+	if !reflect.DeepEqual(res.Dist, want) {
+		t.Errorf("Targets: Dist %v, ожидалось %v", res.Dist, want)
+	}
+	if _, ok := res.Prev[3]; ok {
+		t.Error("Targets: у незафиксированной вершины 3 есть предок")
+	}
+
+	// Цели зафиксированы с теми же расстояниями, что и без раннего выхода
+	res = DijkstraToTargets(dijkstraGraph(), []int{1}, []int{4, 2})
+	if res.Dist[4] != 4 || res.Dist[2] != 1 {
+		t.Errorf("Targets {4, 2}: Dist %v", res.Dist)
+	}
+
+	var visited []int
+	res = DijkstraFrom(dijkstraGraph(), []int{1}, DijkstraOptions{Visit: func(vertex, dist int) bool {
+		visited = append(visited, vertex)
+		return len(visited) < 3
+	}})
+	if !reflect.DeepEqual(visited, []int{1, 2, 3}) {
+		t.Errorf("Visit: порядок %v, ожидался [1 2 3]", visited)
+	}
+	// Вершина 3 зафиксирована, хотя поиск остановился на ней
+	if res.Dist[3] != 3 || res.Dist[4] != inf {
+		t.Errorf("Visit: Dist %v", res.Dist)
+	}
+}
@@ -0,0 +1,121 @@
+package algorithms
+
+import (
+	"sort"
+	"wintersc/graph"
+)
+
+// Параметры HyperANF
+type ANFOptions struct {
+	Log2Registers uint8 // Регистров на вершину 2^Log2Registers, от 4 до 16 (0 — 6)
+	MaxRadius     int   // Наибольший радиус (0 — пока счётчики меняются)
+	Seed          uint64
+}
+
+// Приближённая функция окрестностей графа
+type ANFResult struct {
+	// PerVertex[v][t] — оценка числа вершин на расстоянии не больше t от v
+	PerVertex map[int][]float64
+	// Global[t] — оценка числа пар (u, v) на расстоянии не больше t
+	Global            []float64
+	EffectiveDiameter float64 // Расстояние, в пределах которого 90% достижимых пар
+	AverageDistance   float64 // Среднее расстояние между достижимыми парами
+}
+
+// HyperANF (Boldi, Rosa, Vigna, 2011): у каждой вершины есть счётчик
+// HyperLogLog её шара радиуса t. Шар радиуса t+1 — объединение шаров
+// соседей радиуса t, поэтому каждая итерация стоит O(m · 2^p)
+// вместо BFS из каждой вершины.
+func HyperANF(g graph.View, opts ANFOptions) *ANFResult {
+	p := opts.Log2Registers
+	if p == 0 {
+		p = 6
+	}
+	p = min(max(p, 4), 16)
+
+	vertices := make([]int, 0, g.NumVertices())
+	for u := range g.Vertices() {
+		vertices = append(vertices, u)
+	}
+	sort.Ints(vertices)
+	index := make(map[int]int, len(vertices))
+	for i, u := range vertices {
+		index[u] = i
+	}
+
+	neighbors := make([][]int, len(vertices))
+	for i, u := range vertices {
+		for v := range g.Neighbors(u) {
+			neighbors[i] = append(neighbors[i], index[v])
+		}
+	}
+
+	current := make([]*HyperLogLog, len(vertices))
+	for i, u := range vertices {
+		current[i] = NewHyperLogLog(p)
+		current[i].Add(mix64(uint64(u) ^ opts.Seed))
+	}
+
+	result := &ANFResult{PerVertex: make(map[int][]float64, len(vertices))}
+	record := func() {
+		total := 0.0
+		for i, u := range vertices {
+			estimate := current[i].Estimate()
+			result.PerVertex[u] = append(result.PerVertex[u], estimate)
+			total += estimate
+		}
+		result.Global = append(result.Global, total)
+	}
+	record()
+
+	for t := 1; opts.MaxRadius == 0 || t <= opts.MaxRadius; t++ {
+		next := make([]*HyperLogLog, len(vertices))
+		changed := false
+		for i := range vertices {
+			next[i] = current[i].Clone()
+			for _, j := range neighbors[i] {
+				if next[i].Union(current[j]) {
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+		current = next
+		record()
+	}
+
+	result.EffectiveDiameter = effectiveDiameter(result.Global, 0.9)
+	result.AverageDistance = averageDistance(result.Global)
+	return result
+}
+
+// Наименьший (интерполированный) радиус, покрывающий долю alpha достижимых пар.
+// Пары вершины с самой собой (Global[0]) не учитываются.
+func effectiveDiameter(global []float64, alpha float64) float64 {
+	last := len(global) - 1
+	if last < 1 || global[last] <= global[0] {
+		return 0
+	}
+	target := global[0] + alpha*(global[last]-global[0])
+	for t := 1; t <= last; t++ {
+		if global[t] >= target {
+			// Линейная интерполяция между t-1 и t
+			return float64(t-1) + (target-global[t-1])/(global[t]-global[t-1])
+		}
+	}
+	return float64(last)
+}
+
+func averageDistance(global []float64) float64 {
+	last := len(global) - 1
+	if last < 1 || global[last] <= global[0] {
+		return 0
+	}
+	sum := 0.0
+	for t := 1; t <= last; t++ {
+		sum += float64(t) * (global[t] - global[t-1])
+	}
+	return sum / (global[last] - global[0])
+}
@@ -0,0 +1,85 @@
+package algorithms
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Счётчик HyperLogLog: приближённый размер множества в 2^p байтах
+// с относительной погрешностью около 1.04/sqrt(2^p)
+type HyperLogLog struct {
+	p         uint8
+	Registers []uint8
+}
+
+func NewHyperLogLog(p uint8) *HyperLogLog {
+	return &HyperLogLog{p: p, Registers: make([]uint8, 1<<p)}
+}
+
+// Добавляет элемент по его 64-битному хешу
+func (h *HyperLogLog) Add(hash uint64) {
+	index := hash >> (64 - h.p)
+	// Ранг — позиция первой единицы в оставшихся битах
+	rank := uint8(bits.LeadingZeros64(hash<<h.p|1<<(h.p-1))) + 1
+	if rank > h.Registers[index] {
+		h.Registers[index] = rank
+	}
+}
+
+// Объединяет с другим счётчиком того же размера. Возвращает true, если счётчик изменился.
+func (h *HyperLogLog) Union(other *HyperLogLog) bool {
+	changed := false
+	for i, r := range other.Registers {
+		if r > h.Registers[i] {
+			h.Registers[i] = r
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	registers := make([]uint8, len(h.Registers))
+	copy(registers, h.Registers)
+	return &HyperLogLog{p: h.p, Registers: registers}
+}
+
+// Оценка числа различных элементов
+func (h *HyperLogLog) Estimate() float64 {
+	m := float64(len(h.Registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.Registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := hllAlpha(len(h.Registers)) * m * m / sum
+
+	// Поправка для малых множеств: линейный подсчёт по пустым регистрам
+	if estimate <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
+	}
+	return estimate
+}
+
+func hllAlpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+// Перемешивание splitmix64: хороший 64-битный хеш для целых ключей
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,59 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+)
+
+// Относительная ошибка оценки укладывается в три стандартных отклонения
+// 1.04/sqrt(2^p), в том числе на малых множествах, где работает линейный подсчёт
+func TestHyperLogLogErrorBound(t *testing.T) {
+	for _, p := range []uint8{6, 10, 14} {
+		bound := 3 * 1.04 / math.Sqrt(float64(uint(1)<<p))
+		for _, n := range []int{10, 100, 1000, 10000, 100000} {
+			for seed := 0; seed < 5; seed++ {
+				h := NewHyperLogLog(p)
+				for i := 0; i < n; i++ {
+					h.Add(mix64(uint64(seed)<<32 | uint64(i)))
+				}
+				if rel := math.Abs(h.Estimate()-float64(n)) / float64(n); rel > bound {
+					t.Errorf("p = %d, n = %d, seed %d: оценка %.0f, ошибка %.3f больше %.3f",
+						p, n, seed, h.Estimate(), rel, bound)
+				}
+			}
+		}
+	}
+}
+
+func TestHyperLogLogUnion(t *testing.T) {
+	a, b := NewHyperLogLog(12), NewHyperLogLog(12)
+	for i := 0; i < 3000; i++ {
+		a.Add(mix64(uint64(i)))
+	}
+	for i := 2000; i < 5000; i++ {
+		b.Add(mix64(uint64(i)))
+	}
+
+	// Повторные элементы не меняют счётчик
+	before := a.Clone()
+	for i := 0; i < 3000; i++ {
+		a.Add(mix64(uint64(i)))
+	}
+	if a.Estimate() != before.Estimate() || a.Union(before) {
+		t.Error("повторное добавление изменило счётчик")
+	}
+
+	if !a.Union(b) {
+		t.Fatal("Union не изменил счётчик")
+	}
+	bound := 3 * 1.04 / math.Sqrt(4096)
+	if rel := math.Abs(a.Estimate()-5000) / 5000; rel > bound {
+		t.Errorf("объединение: оценка %.0f, ожидалось около 5000", a.Estimate())
+	}
+	if a.Union(b) {
+		t.Error("повторный Union изменил счётчик")
+	}
+	if before.Estimate() >= a.Estimate() {
+		t.Error("Clone разделяет регистры с оригиналом")
+	}
+}
@@ -0,0 +1,55 @@
+package algorithms
+
+import (
+	"math"
+	"wintersc/graph"
+)
+
+// PageRank для неориентированного графа: каждая вершина делит свой ранг
+// поровну между соседями. Ранг изолированных вершин распределяется по всем.
+// Итерации прекращаются, когда суммарное изменение меньше tol.
+func PageRank(g graph.View, damping float64, maxIter int, tol float64) map[int]float64 {
+	n := g.NumVertices()
+	rank := make(map[int]float64, n)
+	if n == 0 {
+		return rank
+	}
+	for u := range g.Vertices() {
+		rank[u] = 1 / float64(n)
+	}
+
+	for iter := 0; iter < maxIter; iter++ {
+		// Ранг "висячих" вершин без соседей
+		dangling := 0.0
+		for u := range g.Vertices() {
+			if g.Degree(u) == 0 {
+				dangling += rank[u]
+			}
+		}
+
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		next := make(map[int]float64, n)
+		for u := range g.Vertices() {
+			next[u] += base
+			deg := g.Degree(u)
+			if deg == 0 {
+				continue
+			}
+			share := damping * rank[u] / float64(deg)
+			for v := range g.Neighbors(u) {
+				next[v] += share
+			}
+		}
+
+		diff := 0.0
+		for u, r := range next {
+			diff += math.Abs(r - rank[u])
+		}
+		rank = next
+		if diff < tol {
+			break
+		}
+	}
+
+	return rank
+}
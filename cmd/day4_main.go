@@ -1,37 +1,71 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 	"wintersc/distributed"
 )
 
 func main() {
 	// Создаём 3 узла Raft
-	node1 := distributed.NewRaftNode(1, nil)
-	node2 := distributed.NewRaftNode(2, nil)
-	node3 := distributed.NewRaftNode(3, nil)
-
-	// Связываем узлы
-	node1.Nodes = []*distributed.RaftNode{node2, node3}
-	node2.Nodes = []*distributed.RaftNode{node1, node3}
-	node3.Nodes = []*distributed.RaftNode{node1, node2}
+	nodes := distributed.InitializeNodes([]int{1, 2, 3})
+	for _, node := range nodes {
+		node.ApplyCh = make(chan distributed.ApplyMsg, 100)
+	}
 
 	// Запускаем горутины для каждого узла
-	go node1.drun()
-	go node2.run()
-	go node3.run()
+	// Узлы обмениваются сообщениями через сеть внутри процесса
+	network := distributed.NewMemoryNetwork[distributed.MessageRaft](100)
+	var wg sync.WaitGroup
+	for id, node := range nodes {
+		wg.Add(1)
+		go node.Run(&wg, network.Endpoint(id))
+	}
+
+	// Печатаем применённые команды каждого узла
+	for id, node := range nodes {
+		go func() {
+			for msg := range node.ApplyCh {
+				fmt.Printf("Node %d: применена запись %d (срок %d): %v\n", id, msg.Index, msg.Term, msg.Command)
+			}
+		}()
+	}
 
-	// Симулируем клиент, который отправляет команды лидеру
-	time.Sleep(2 * time.Second)
-	node1.distributedsendMessage(node1.LeaderID, "AppendEntries", node1.Term, "commandX")
+	// Симулируем клиент, который отправляет команды, начиная со случайного узла
+	time.Sleep(time.Second)
+	for seq, command := range []string{"commandX", "commandY"} {
+		req := distributed.ClientRequest{ClientID: 42, Seq: int64(seq + 1), Command: command}
+		index, term := propose(nodes, 1, req)
+		fmt.Printf("Клиент: %q закоммичена с индексом %d в сроке %d\n", command, index, term)
+	}
 
-	// Падение лидера (например, узел 1)
-	time.Sleep(3 * time.Second)
-	node1.distributedfailLeader()
+	// Повтор уже выполненного запроса не создаёт новой записи
+	index, term := propose(nodes, 1, distributed.ClientRequest{ClientID: 42, Seq: 2, Command: "commandY"})
+	fmt.Printf("Клиент: повтор commandY вернул индекс %d в сроке %d\n", index, term)
+
+	time.Sleep(500 * time.Millisecond)
+}
 
-	// Ждем, пока новый лидер не станет лидером
-	time.Sleep(2 * time.Second)
+// Отправляет запрос, следуя подсказкам NotLeaderError и повторяя при смене лидера
+func propose(nodes map[int]*distributed.Node, target int, req distributed.ClientRequest) (int, int) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		index, term, err := nodes[target].Propose(ctx, req)
+		cancel()
 
-	// Снова отправляем команду
-	node2.distributed.sendMessage(node2.LeaderID, "AppendEntries", node2.Term, "commandY")
+		var notLeader *distributed.NotLeaderError
+		switch {
+		case err == nil:
+			return index, term
+		case errors.As(err, &notLeader) && notLeader.LeaderID != -1:
+			fmt.Printf("Клиент: узел %d не лидер, перенаправляю на %d\n", target, notLeader.LeaderID)
+			target = notLeader.LeaderID
+		default:
+			fmt.Printf("Клиент: %v, повторяю\n", err)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"wintersc/generator"
+	"wintersc/graph"
+	"wintersc/linkpred"
+)
+
+// Сравнение эвристик рекомендации друзей.
+//
+//	go run cmd/day6_main.go -n 2000 -m 4 -temporal
+//	go run cmd/day6_main.go -input edges.txt -scorer adamic_adar
+//
+// Файл рёбер: строки "u v [вес [время]]", строки с # пропускаются.
+func main() {
+	input := flag.String("input", "", "файл со списком рёбер (по умолчанию граф Барабаши–Альберт)")
+	n := flag.Int("n", 2000, "число вершин генерируемого графа")
+	m := flag.Int("m", 4, "рёбер на новую вершину в генерируемом графе")
+	testFraction := flag.Float64("test", 0.1, "доля рёбер в тесте")
+	temporal := flag.Bool("temporal", false, "в тест идут самые поздние рёбра")
+	negatives := flag.Int("neg", 5, "отрицательных пар на тестовое ребро")
+	k := flag.Int("k", 100, "k для precision@k и recall@k")
+	scorer := flag.String("scorer", "all", "эвристика или all")
+	seed := flag.Int64("seed", 1, "seed")
+	flag.Parse()
+
+	var g *graph.Graph
+	if *input != "" {
+		var err error
+		g, err = readEdgeList(*input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else {
+		// Порядок добавления рёбер совпадает с ростом графа, поэтому
+		// временное разбиение отправит в тест рёбра самых новых вершин
+		g = generator.BarabasiAlbert(*n, *m, generator.Options{Seed: *seed})
+		for i := range g.Edge {
+			g.Edge[i].Created = int64(i)
+		}
+	}
+
+	train, test := linkpred.Split(g, linkpred.SplitOptions{TestFraction: *testFraction, Temporal: *temporal, Seed: *seed})
+	pairs := linkpred.NegativeSamples(g, test, *negatives, *seed)
+	fmt.Printf("Вершин: %d, рёбер для обучения: %d, тестовых рёбер: %d, отрицательных пар: %d\n\n",
+		train.NumVertices(), train.NumEdges(), len(test), len(pairs))
+
+	names := []string{}
+	if *scorer == "all" {
+		for name := range linkpred.Scorers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else if _, ok := linkpred.Scorers[*scorer]; ok {
+		names = append(names, *scorer)
+	} else {
+		fmt.Fprintf(os.Stderr, "неизвестная эвристика %q\n", *scorer)
+		os.Exit(1)
+	}
+
+	results := []linkpred.Result{}
+	for _, name := range names {
+		r := linkpred.Evaluate(train, test, pairs, linkpred.Scorers[name], *k)
+		r.Name = name
+		results = append(results, r)
+	}
+	linkpred.WriteTable(os.Stdout, results)
+}
+
+func readEdgeList(path string) (*graph.Graph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	g := graph.NewGraph()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: ожидается \"u v [вес [время]]\"", path, line)
+		}
+		values := []int64{0, 0, 1, 0}
+		for i := 0; i < len(fields) && i < 4; i++ {
+			values[i], err = strconv.ParseInt(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+		}
+		g.AddEdgeAt(int(values[0]), int(values[1]), int(values[2]), values[3])
+	}
+	return g, scanner.Err()
+}
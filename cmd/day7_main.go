@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wintersc/distributed"
+)
+
+// Один узел кластера в отдельном процессе; узлы общаются по TCP.
+// Например, кластер Raft из трёх процессов:
+//
+//	go run cmd/day7_main.go -id 1 -peers 1=127.0.0.1:7001,2=127.0.0.1:7002,3=127.0.0.1:7003
+//	go run cmd/day7_main.go -id 2 -peers ...
+//	go run cmd/day7_main.go -id 3 -peers ...
+func main() {
+	proto := flag.String("proto", "raft", "протокол: raft, bully или ring")
+	id := flag.Int("id", 1, "ID этого узла")
+	peerList := flag.String("peers", "", "адреса всех узлов, включая этот: id=host:port,...")
+	dir := flag.String("dir", "", "каталог для долговременного состояния Raft (пусто — в памяти)")
+	duration := flag.Duration("duration", 20*time.Second, "сколько работать")
+	flag.Parse()
+
+	addrs, err := parsePeers(*peerList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := addrs[*id]; !ok {
+		log.Fatalf("адрес узла %d не задан в -peers", *id)
+	}
+	ids := make([]int, 0, len(addrs))
+	for peer := range addrs {
+		ids = append(ids, peer)
+	}
+	sort.Ints(ids)
+
+	switch *proto {
+	case "raft":
+		runRaft(*id, ids, addrs, *dir, *duration)
+	case "bully":
+		runBully(*id, ids, addrs, *duration)
+	case "ring":
+		runRing(*id, ids, addrs, *duration)
+	default:
+		log.Fatalf("неизвестный протокол %q", *proto)
+	}
+}
+
+func runRaft(id int, ids []int, addrs map[int]string, dir string, duration time.Duration) {
+	var storage distributed.RaftStorage = distributed.NewMemoryStorage()
+	if dir != "" {
+		fs, err := distributed.NewFileStorage(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Узел закроет хранилище, когда Run завершится
+		storage = fs
+	}
+
+	node, err := distributed.NewNode(id, others(ids, id), storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	node.ApplyCh = make(chan distributed.ApplyMsg, 100)
+	go func() {
+		for msg := range node.ApplyCh {
+			fmt.Printf("Узел %d: применена запись %d (срок %d): %v\n", id, msg.Index, msg.Term, msg.Command)
+		}
+	}()
+
+	transport, err := distributed.NewTCPTransport[distributed.MessageRaft](id, addrs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go node.Run(&wg, transport)
+
+	// Раз в секунду пытаемся предложить команду; это получится только у лидера
+	deadline := time.Now().Add(duration)
+	for seq := 1; time.Now().Before(deadline); seq++ {
+		time.Sleep(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		command := fmt.Sprintf("команда %d от узла %d", seq, id)
+		index, term, err := node.Propose(ctx, command)
+		cancel()
+
+		var notLeader *distributed.NotLeaderError
+		switch {
+		case err == nil:
+			fmt.Printf("Узел %d: %q закоммичена с индексом %d в сроке %d\n", id, command, index, term)
+		case errors.As(err, &notLeader):
+			fmt.Printf("Узел %d: лидер %d\n", id, notLeader.LeaderID)
+		default:
+			fmt.Printf("Узел %d: %v\n", id, err)
+		}
+	}
+
+	transport.Close()
+	wg.Wait()
+}
+
+func runBully(id int, ids []int, addrs map[int]string, duration time.Duration) {
+	transport, err := distributed.NewTCPTransport[distributed.Msg](id, addrs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer transport.Close()
+
+	node := distributed.NewBullyNode(id)
+	node.Connect(transport, others(ids, id))
+	time.Sleep(duration)
+
+	node.Mutex.Lock()
+	fmt.Printf("Узел %d: мой лидер %d\n", id, node.LeaderID)
+	node.Mutex.Unlock()
+}
+
+func runRing(id int, ids []int, addrs map[int]string, duration time.Duration) {
+	transport, err := distributed.NewTCPTransport[distributed.Message](id, addrs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer transport.Close()
+
+	next := ids[0]
+	for i, other := range ids {
+		if other == id {
+			next = ids[(i+1)%len(ids)]
+		}
+	}
+	node := distributed.NewRingNode(id)
+	node.Connect(transport, next, ids)
+
+	// Выборы начинает узел с наименьшим ID, когда остальные уже запущены
+	if id == ids[0] {
+		time.Sleep(2 * time.Second)
+		node.StartRingElection()
+	}
+	time.Sleep(duration)
+
+	node.Mutex.Lock()
+	fmt.Printf("Узел %d: мой лидер %d\n", id, node.LeaderID)
+	node.Mutex.Unlock()
+}
+
+func parsePeers(list string) (map[int]string, error) {
+	addrs := make(map[int]string)
+	for _, item := range strings.Split(list, ",") {
+		if item == "" {
+			continue
+		}
+		idText, addr, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("ожидалось id=host:port, получено %q", item)
+		}
+		peer, err := strconv.Atoi(idText)
+		if err != nil {
+			return nil, fmt.Errorf("неверный ID узла %q", idText)
+		}
+		addrs[peer] = addr
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("не заданы узлы: используйте -peers")
+	}
+	return addrs, nil
+}
+
+func others(ids []int, exclude int) []int {
+	var result []int
+	for _, id := range ids {
+		if id != exclude {
+			result = append(result, id)
+		}
+	}
+	return result
+}
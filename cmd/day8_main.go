@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"wintersc/distributed"
+)
+
+// Случайные сценарии для кластера Raft в симуляции: потери, дубли,
+// переупорядочивание, партиции и падения узлов. После каждого события
+// проверяется, что ни в одном сроке нет двух лидеров. Упавший сценарий
+// воспроизводится точно: go run cmd/day8_main.go -seed N -runs 1 -trace
+func main() {
+	seed := flag.Int64("seed", 1, "seed первого прогона")
+	runs := flag.Int("runs", 20, "число прогонов с seed, seed+1, ...")
+	duration := flag.Duration("duration", 30*time.Second, "виртуальное время одного прогона")
+	trace := flag.Bool("trace", false, "печатать журнал событий прогона")
+	flag.Parse()
+
+	failed := 0
+	for i := 0; i < *runs; i++ {
+		s := *seed + int64(i)
+		log, err := runScenario(s, *duration)
+		if *trace {
+			os.Stdout.Write(log)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("seed %d: %v\n", s, err)
+			continue
+		}
+		// Повторный прогон с тем же seed должен дать тот же журнал
+		again, _ := runScenario(s, *duration)
+		if !bytes.Equal(log, again) {
+			failed++
+			fmt.Printf("seed %d: прогон не воспроизводится\n", s)
+			continue
+		}
+		fmt.Printf("seed %d: ok (%d строк журнала)\n", s, bytes.Count(log, []byte("\n")))
+	}
+	if failed > 0 {
+		fmt.Printf("%d из %d прогонов с ошибками\n", failed, *runs)
+		os.Exit(1)
+	}
+}
+
+func runScenario(seed int64, duration time.Duration) ([]byte, error) {
+	var log bytes.Buffer
+	sim := distributed.NewSimulation(seed, distributed.SimOptions{
+		Latency:       distributed.ExponentialLatency(5 * time.Millisecond),
+		DropRate:      0.05,
+		DuplicateRate: 0.02,
+		ReorderRate:   0.1,
+		Trace:         &log,
+	})
+	ids := []int{1, 2, 3, 4, 5}
+	cluster := distributed.NewSimRaftCluster(sim, ids, nil)
+	rnd := sim.Rand()
+
+	leaders := map[int]int{} // срок → лидер
+	var violation error
+	check := func() bool {
+		for _, id := range ids {
+			if sim.Crashed(id) {
+				continue
+			}
+			node := cluster.Nodes[id]
+			if node.State != distributed.Leader {
+				continue
+			}
+			if other, ok := leaders[node.CurrentTerm]; ok && other != id {
+				violation = fmt.Errorf("два лидера в сроке %d: %d и %d", node.CurrentTerm, other, id)
+				return true
+			}
+			leaders[node.CurrentTerm] = id
+		}
+		return false
+	}
+
+	// Каждые полсекунды виртуального времени — случайный сбой или восстановление
+	for sim.Elapsed() < duration && violation == nil {
+		switch rnd.Intn(5) {
+		case 0:
+			cut := 1 + rnd.Intn(len(ids)-1)
+			perm := rnd.Perm(len(ids))
+			var left, right []int
+			for i, p := range perm {
+				if i < cut {
+					left = append(left, ids[p])
+				} else {
+					right = append(right, ids[p])
+				}
+			}
+			sim.Partition(left, right)
+		case 1:
+			sim.Heal()
+		case 2:
+			sim.Crash(ids[rnd.Intn(len(ids))])
+		case 3:
+			sim.Restart(ids[rnd.Intn(len(ids))])
+		}
+		sim.RunUntil(check, 500*time.Millisecond)
+	}
+	return log.Bytes(), violation
+}
@@ -11,17 +11,20 @@ type BullyNode struct {
 	ID           int
 	LeaderID     int
 	Alive        bool
-	LocalData    int // Число пользователей
-	Inbox        chan Msg
-	BullyNodes   []*BullyNode
+	LocalData    int   // Число пользователей
+	BullyNodes   []int // ID остальных узлов
 	Mutex        sync.Mutex
 	IsListen     bool
+	// Куда писать журнал событий узла (nil — стандартный вывод)
+	Logger    func(format string, args ...interface{})
+	transport Transport[Msg]
+	clock     Clock
 }
 
 type Msg struct {
-	Kind string     // "ELECTION", "COORDINATOR", "COLLECT", "COLLECT_REPLY"
-	From *BullyNode // ID отправителя
-	Data int        // Локальные данные (для сбора)
+	Kind string // "ELECTION", "COORDINATOR", "COLLECT", "COLLECT_REPLY"
+	From int    // ID отправителя
+	Data int    // Локальные данные (для сбора)
 }
 
 func NewBullyNode(id int) *BullyNode {
@@ -30,94 +33,133 @@ func NewBullyNode(id int) *BullyNode {
 		ID:           id,
 		LeaderID:     -1,
 		Alive:        true,
-		Inbox:        make(chan Msg, 10),
-		BullyNodes:   make([]*BullyNode, 0),
+		BullyNodes:   make([]int, 0),
 		HasNeighbour: true,
 	}
 }
 
-func (n *BullyNode) Listen() {
-	timer := time.NewTimer(3 * time.Second)
-	for {
-		select {
-		case msg := <-n.Inbox:
-			switch msg.Kind {
-			case "ELECTION":
-				n.HandleElection(msg)
-			case "COORDINATOR":
-				n.HandleCoordinator(msg)
-			case "OK":
-				n.HandleOk(msg)
-
+// Связывает узлы внутри процесса и запускает их
+func SetupBully(nodes []*BullyNode) {
+	network := NewMemoryNetwork[Msg](10)
+	for _, node := range nodes {
+		var peers []int
+		for _, other := range nodes {
+			if other.ID != node.ID {
+				peers = append(peers, other.ID)
 			}
-		case <-timer.C:
-			n.StartBullyElection()
 		}
+		node.Connect(network.Endpoint(node.ID), peers)
 	}
 }
 
-func (node *BullyNode) AddConnectBully(nodes []*BullyNode) {
-	node.BullyNodes = append(node.BullyNodes, nodes...)
+// Подключает узел к остальным через transport и запускает обработку сообщений
+func (node *BullyNode) Connect(transport Transport[Msg], peers []int) {
+	node.attach(transport, realClock{}, peers)
 
 	if !node.IsListen {
+		node.IsListen = true
 		go node.Listen()
 	}
 }
 
-func SendMessage(to *BullyNode, msg Msg) {
+// Запоминает сеть и часы и заводит таймер первых выборов
+func (node *BullyNode) attach(transport Transport[Msg], clock Clock, peers []int) {
+	node.transport = transport
+	node.clock = clock
+	node.BullyNodes = append([]int(nil), peers...)
+	clock.AfterFunc(3*time.Second, node.StartBullyElection)
+}
 
-	to.Inbox <- msg
+func (n *BullyNode) Listen() {
+	for msg := range n.transport.Receive() {
+		n.Handle(msg)
+	}
+}
 
+// Обрабатывает одно входящее сообщение
+func (n *BullyNode) Handle(msg Msg) {
+	switch msg.Kind {
+	case "ELECTION":
+		n.HandleElection(msg)
+	case "COORDINATOR":
+		n.HandleCoordinator(msg)
+	case "OK":
+		n.HandleOk(msg)
+	}
+}
+
+func (n *BullyNode) SendMessage(to int, msg Msg) {
+	if err := n.transport.Send(to, msg); err != nil {
+		n.logf("%d: Не удалось отправить сообщение %d: %v\n", n.ID, to, err)
+	}
 }
 
 func (n *BullyNode) StartBullyElection() {
+	// Пока не пришёл OK от старшего узла, считаем лидером себя
+	n.Mutex.Lock()
+	n.HasNeighbour = true
+	n.Mutex.Unlock()
+
 	for _, neighbour := range n.BullyNodes {
-		if neighbour.ID > n.ID {
-			SendMessage(neighbour, Msg{Kind: "ELECTION", From: n})
+		if neighbour > n.ID {
+			n.SendMessage(neighbour, Msg{Kind: "ELECTION", From: n.ID})
 		}
 	}
 
-	time.Sleep(2 * time.Second)
+	// Ждём ответов по таймеру, не блокируя обработку сообщений
+	n.clock.AfterFunc(2*time.Second, n.finishElection)
+}
+
+func (n *BullyNode) finishElection() {
+	n.Mutex.Lock()
+	leader := n.HasNeighbour
+	if leader {
+		n.LeaderID = n.ID
+	}
+	n.Mutex.Unlock()
 
-	if n.HasNeighbour {
+	if leader {
 		for _, neighbour := range n.BullyNodes {
-			SendMessage(neighbour, Msg{Kind: "COORDINATOR", From: n})
-			n.Mutex.Lock()
-			n.LeaderID = n.ID
-			n.Mutex.Unlock()
+			n.SendMessage(neighbour, Msg{Kind: "COORDINATOR", From: n.ID})
 		}
 	}
 }
 
+func (n *BullyNode) logf(format string, args ...interface{}) {
+	if n.Logger != nil {
+		n.Logger(format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
 func (n *BullyNode) HandleOk(msg Msg) {
-	fmt.Printf("%d: Пришел OK от %d\n", n.ID, msg.From.ID)
+	n.logf("%d: Пришел OK от %d\n", n.ID, msg.From)
 	if !n.Alive {
 		n.Mutex.Lock()
 		n.HasNeighbour = false
 		n.Mutex.Unlock()
 	}
-	if n.ID < msg.From.ID {
+	if n.ID < msg.From {
 		n.Mutex.Lock()
 		n.HasNeighbour = false
 		n.Mutex.Unlock()
-		fmt.Println(n.HasNeighbour)
 	} else {
 		n.Mutex.Lock()
 		n.HasNeighbour = true
 		n.Mutex.Unlock()
-		fmt.Println(n.HasNeighbour)
 	}
 
 }
 
 func (n *BullyNode) HandleElection(msg Msg) {
-	fmt.Printf("%d: Пришел ELECTION от %d\n", n.ID, msg.From.ID)
+	n.logf("%d: Пришел ELECTION от %d\n", n.ID, msg.From)
 	if n.Alive {
-		SendMessage(msg.From, Msg{Kind: "OK", From: n})
+		n.SendMessage(msg.From, Msg{Kind: "OK", From: n.ID})
 
 		for _, neighbour := range n.BullyNodes {
-			if neighbour.ID > n.ID {
-				SendMessage(neighbour, Msg{Kind: "ELECTION", From: n})
+			if neighbour > n.ID {
+				n.SendMessage(neighbour, Msg{Kind: "ELECTION", From: n.ID})
 			}
 		}
 	} else {
@@ -126,8 +168,8 @@ func (n *BullyNode) HandleElection(msg Msg) {
 }
 
 func (n *BullyNode) HandleCoordinator(msg Msg) {
-	fmt.Printf("%d: Пришел COORDINATOR от %d\n", n.ID, msg.From.ID)
+	n.logf("%d: Пришел COORDINATOR от %d\n", n.ID, msg.From)
 	n.Mutex.Lock()
-	n.LeaderID = msg.From.ID
+	n.LeaderID = msg.From
 	n.Mutex.Unlock()
 }
@@ -0,0 +1,26 @@
+package distributed
+
+import "time"
+
+// Источник времени для таймеров узлов. В обычной работе это реальное время,
+// в симуляции — виртуальное, которое двигает Simulation.
+type Clock interface {
+	Now() time.Time
+	// Вызывает f через d. В реальном времени f выполняется в своей горутине.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type Timer interface {
+	// Отменяет таймер; false, если он уже сработал или отменён
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
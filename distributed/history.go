@@ -0,0 +1,65 @@
+package distributed
+
+import (
+	"sync"
+	"time"
+)
+
+// Операция клиента в истории: вызов с аргументом Input в момент Call
+// и ответ Output в момент Return
+type Operation struct {
+	ClientID int
+	Input    interface{}
+	Output   interface{}
+	Call     time.Time
+	Return   time.Time // Нулевое, если ответ не получен
+}
+
+// Ответ не получен (таймаут, сбой): операция могла выполниться в любой
+// момент после Call или не выполниться вовсе
+func (op Operation) Pending() bool {
+	return op.Return.IsZero()
+}
+
+// Журнал операций клиентов для проверки линеаризуемости, как в Jepsen:
+// клиент записывает вызов до отправки запроса и ответ после его получения.
+// Безопасен для одновременного использования из нескольких горутин.
+type History struct {
+	mutex sync.Mutex
+	clock Clock
+	ops   []Operation
+}
+
+// clock — источник времени вызовов и ответов: nil для реального времени,
+// Simulation для виртуального
+func NewHistory(clock Clock) *History {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &History{clock: clock}
+}
+
+// Записывает вызов операции и возвращает её номер для Complete
+func (h *History) Invoke(clientID int, input interface{}) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.ops = append(h.ops, Operation{ClientID: clientID, Input: input, Call: h.clock.Now()})
+	return len(h.ops) - 1
+}
+
+// Записывает ответ на операцию id. Операции, на которые ответ так и не
+// пришёл, остаются в истории без ответа: удалять их нельзя, ведь они
+// могли выполниться.
+func (h *History) Complete(id int, output interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.ops[id].Output = output
+	h.ops[id].Return = h.clock.Now()
+}
+
+// Копия записанных операций в порядке вызова
+func (h *History) Operations() []Operation {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]Operation(nil), h.ops...)
+}
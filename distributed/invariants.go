@@ -0,0 +1,190 @@
+package distributed
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Проверка свойств безопасности Raft (рис. 3 статьи) на кластере
+// в симуляции. Свойства касаются всей истории, поэтому Check нужно вызывать
+// после каждого события симуляции, например как условие RunUntil:
+//
+//	sim.RunUntil(func() bool { err = inv.Check(); return err != nil }, d)
+type RaftInvariants struct {
+	cluster *SimRaftCluster
+	leaders map[int]int // Election Safety: срок → лидер
+	// Leader Append-Only: лог лидера срока при прошлой проверке
+	leaderLogs map[leaderKey]observedLog
+	// State Machine Safety: запись, применённая под индексом первым узлом
+	applied map[int]LogEntry
+	checked map[*Node]int // Индекс, до которого проверены применённые записи узла
+	// Состояние узлов при прошлой проверке: если ничего не изменилось,
+	// свойства проверять заново не нужно
+	seen map[int]nodeVersion
+}
+
+type leaderKey struct {
+	id, term int
+}
+
+// Сроки записей лога начиная с from
+type observedLog struct {
+	from  int
+	terms []int
+}
+
+type nodeVersion struct {
+	node                        *Node
+	state                       Role
+	term, last, lastTerm        int
+	commit, applied, snapshotAt int
+}
+
+func NewRaftInvariants(c *SimRaftCluster) *RaftInvariants {
+	return &RaftInvariants{
+		cluster:    c,
+		leaders:    make(map[int]int),
+		leaderLogs: make(map[leaderKey]observedLog),
+		applied:    make(map[int]LogEntry),
+		checked:    make(map[*Node]int),
+		seen:       make(map[int]nodeVersion),
+	}
+}
+
+// Проверяет свойства на текущем состоянии работающих узлов и возвращает
+// первое найденное нарушение:
+//   - Election Safety: в каждом сроке не больше одного лидера;
+//   - Leader Append-Only: лидер не удаляет и не заменяет записи своего лога;
+//   - Log Matching: если у двух логов совпадают индекс и срок записи,
+//     то совпадают и все записи до неё;
+//   - State Machine Safety: под одним индексом все узлы применяют одну и ту же запись.
+func (inv *RaftInvariants) Check() error {
+	changed := false
+	for _, id := range inv.cluster.ids {
+		if inv.cluster.Sim.Crashed(id) {
+			continue
+		}
+		n := inv.cluster.Nodes[id]
+		version := nodeVersion{n, n.State, n.CurrentTerm, n.lastLogIndex(), n.getLastLogTerm(),
+			n.CommitIndex, n.LastApplied, n.snapshotIndex}
+		if inv.seen[id] != version {
+			inv.seen[id] = version
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	for _, id := range inv.cluster.ids {
+		if inv.cluster.Sim.Crashed(id) {
+			continue
+		}
+		n := inv.cluster.Nodes[id]
+		if err := inv.checkLeader(n); err != nil {
+			return err
+		}
+		if err := inv.checkApplied(n); err != nil {
+			return err
+		}
+	}
+	return inv.checkLogMatching()
+}
+
+func (inv *RaftInvariants) checkLeader(n *Node) error {
+	if n.State != Leader {
+		return nil
+	}
+	if other, ok := inv.leaders[n.CurrentTerm]; ok && other != n.ID {
+		return fmt.Errorf("Election Safety: два лидера в сроке %d: %d и %d", n.CurrentTerm, other, n.ID)
+	}
+	inv.leaders[n.CurrentTerm] = n.ID
+
+	key := leaderKey{n.ID, n.CurrentTerm}
+	if old, ok := inv.leaderLogs[key]; ok {
+		if last := old.from + len(old.terms) - 1; last > n.lastLogIndex() {
+			return fmt.Errorf("Leader Append-Only: лог лидера %d в сроке %d укоротился с %d до %d записей",
+				n.ID, n.CurrentTerm, last, n.lastLogIndex())
+		}
+		for i, term := range old.terms {
+			index := old.from + i
+			if index > n.snapshotIndex && n.logTerm(index) != term {
+				return fmt.Errorf("Leader Append-Only: лидер %d в сроке %d заменил запись %d срока %d записью срока %d",
+					n.ID, n.CurrentTerm, index, term, n.logTerm(index))
+			}
+		}
+	}
+	observed := observedLog{from: n.snapshotIndex + 1, terms: make([]int, len(n.Log))}
+	for i, entry := range n.Log {
+		observed.terms[i] = entry.Term
+	}
+	inv.leaderLogs[key] = observed
+	return nil
+}
+
+// Сверяет записи, применённые узлом с прошлой проверки, с записями,
+// применёнными под теми же индексами другими узлами
+func (inv *RaftInvariants) checkApplied(n *Node) error {
+	from, ok := inv.checked[n]
+	if !ok {
+		// Перезапущенный узел начинает применять с последнего снимка
+		from = n.snapshotIndex
+	}
+	for index := from + 1; index <= n.LastApplied; index++ {
+		var entry LogEntry
+		switch {
+		case index > n.snapshotIndex:
+			entry = n.entry(index)
+		case index == n.snapshotIndex:
+			// Запись вошла в снимок в том же событии: известен только её срок
+			if first, ok := inv.applied[index]; ok && first.Term != n.snapshotTerm {
+				return fmt.Errorf("State Machine Safety: узел %d применил под индексом %d запись срока %d, а другой узел — срока %d",
+					n.ID, index, n.snapshotTerm, first.Term)
+			}
+			continue
+		default:
+			continue
+		}
+		first, ok := inv.applied[index]
+		if !ok {
+			inv.applied[index] = entry
+			continue
+		}
+		if first.Term != entry.Term || !reflect.DeepEqual(first.Command, entry.Command) {
+			return fmt.Errorf("State Machine Safety: узел %d применил под индексом %d %v (срок %d), а другой узел — %v (срок %d)",
+				n.ID, index, entry.Command, entry.Term, first.Command, first.Term)
+		}
+	}
+	inv.checked[n] = n.LastApplied
+	return nil
+}
+
+func (inv *RaftInvariants) checkLogMatching() error {
+	var nodes []*Node
+	for _, id := range inv.cluster.ids {
+		if !inv.cluster.Sim.Crashed(id) {
+			nodes = append(nodes, inv.cluster.Nodes[id])
+		}
+	}
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			// Последний общий индекс с одинаковым сроком; записи до снимков не видны
+			from := max(a.snapshotIndex, b.snapshotIndex) + 1
+			match := 0
+			for index := min(a.lastLogIndex(), b.lastLogIndex()); index >= from; index-- {
+				if a.logTerm(index) == b.logTerm(index) {
+					match = index
+					break
+				}
+			}
+			for index := from; index <= match; index++ {
+				x, y := a.entry(index), b.entry(index)
+				if x.Term != y.Term || !reflect.DeepEqual(x.Command, y.Command) {
+					return fmt.Errorf("Log Matching: записи %d узлов %d и %d совпадают, а записи %d различаются: %v (срок %d) и %v (срок %d)",
+						match, a.ID, b.ID, index, x.Command, x.Term, y.Command, y.Term)
+				}
+			}
+		}
+	}
+	return nil
+}
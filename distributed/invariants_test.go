@@ -0,0 +1,91 @@
+package distributed
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Случайные сценарии для кластера Raft с нагрузкой: клиенты пишут
+// в хранилище ключ-значение, пока сеть теряет, дублирует и переставляет
+// сообщения, делится на партиции, а узлы падают и перезапускаются.
+// После каждого события симуляции проверяются Election Safety, Leader
+// Append-Only, Log Matching и State Machine Safety. Сценарии прогоняются
+// с PreVote и CheckQuorum вместе и без обоих: без PreVote лидера выбирают
+// только правила RequestVote, а без CheckQuorum отрезанный лидер остаётся
+// лидером. Упавший сценарий воспроизводится:
+//
+//	go test ./distributed -run TestRaftInvariants -seed 7 -v
+func TestRaftInvariants(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprint("prevote+checkquorum=", enabled), func(t *testing.T) {
+			for _, seed := range testSeeds(10) {
+				t.Run(fmt.Sprint("seed=", seed), func(t *testing.T) {
+					log, applied, err := runInvariants(seed, 20*time.Second, enabled)
+					if err != nil {
+						if testing.Verbose() {
+							t.Logf("журнал событий:\n%s", log)
+						}
+						t.Fatal(err)
+					}
+					if applied == 0 {
+						t.Error("не применено ни одной команды")
+					}
+				})
+			}
+		})
+	}
+}
+
+// enabled включает у всех узлов и PreVote, и CheckQuorum
+func runInvariants(seed int64, duration time.Duration, enabled bool) ([]byte, int, error) {
+	var log bytes.Buffer
+	sim := NewSimulation(seed, SimOptions{
+		Latency:       ExponentialLatency(5 * time.Millisecond),
+		DropRate:      0.05,
+		DuplicateRate: 0.02,
+		ReorderRate:   0.1,
+		Trace:         &log,
+	})
+	ids := []int{1, 2, 3, 4, 5}
+	cluster := NewSimRaftCluster(sim, ids, func(n *Node) {
+		n.StateMachine = NewKVStateMachine()
+		n.SnapshotThreshold = 30
+		n.PreVote = enabled
+		n.CheckQuorum = enabled
+	})
+	invariants := NewRaftInvariants(cluster)
+	rnd := sim.Rand()
+
+	// Три клиента непрерывно пишут в несколько ключей
+	applied := 0
+	var addrs []int
+	for c := 1; c <= 3; c++ {
+		client := cluster.NewKVClient(int64(c))
+		addrs = append(addrs, client.Addr())
+		id, seq := c, 0
+		var next func()
+		next = func() {
+			seq++
+			cmd := KVCommand{Op: "put", Key: fmt.Sprintf("k%d", rnd.Intn(5)), Value: fmt.Sprintf("%d.%d", id, seq)}
+			client.Do(cmd, func(KVResult) {
+				applied++
+				sim.AfterFunc(time.Duration(rnd.Intn(20))*time.Millisecond, next)
+			})
+		}
+		next()
+	}
+
+	var violation error
+	check := func() bool {
+		violation = invariants.Check()
+		return violation != nil
+	}
+	// Каждые полсекунды виртуального времени — случайный сбой или восстановление
+	for sim.Elapsed() < duration && violation == nil {
+		randomFault(sim, ids, addrs)
+		sim.RunUntil(check, 500*time.Millisecond)
+	}
+	return log.Bytes(), applied, violation
+}
@@ -0,0 +1,228 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Команда реплицируемого хранилища ключ-значение
+type KVCommand struct {
+	Op           string // "get", "put", "delete", "cas", "scan"
+	Key          string
+	Value        string
+	Expected     string // Для "cas": ожидаемое текущее значение
+	ExpectAbsent bool   // Для "cas": ключа не должно быть
+	End          string // Для "scan": конец диапазона [Key, End), пустой — до конца
+	Limit        int    // Для "scan": наибольшее число пар (0 — без ограничения)
+}
+
+type KVPair struct {
+	Key, Value string
+}
+
+// Результат команды хранилища
+type KVResult struct {
+	Value string
+	Found bool     // Ключ существовал до выполнения команды
+	OK    bool     // Для "cas": значение заменено
+	Pairs []KVPair // Для "scan"
+}
+
+// Автомат хранилища ключ-значение
+type KVStateMachine struct {
+	mutex sync.RWMutex // Get может читать из другой горутины, пока узел применяет лог
+	data  map[string]string
+}
+
+func NewKVStateMachine() *KVStateMachine {
+	return &KVStateMachine{data: make(map[string]string)}
+}
+
+func (kv *KVStateMachine) Apply(command interface{}) interface{} {
+	cmd, ok := command.(KVCommand)
+	if !ok {
+		return KVResult{}
+	}
+
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	value, found := kv.data[cmd.Key]
+	result := KVResult{Value: value, Found: found}
+	switch cmd.Op {
+	case "put":
+		kv.data[cmd.Key] = cmd.Value
+	case "delete":
+		delete(kv.data, cmd.Key)
+	case "cas":
+		if (cmd.ExpectAbsent && !found) || (!cmd.ExpectAbsent && found && value == cmd.Expected) {
+			kv.data[cmd.Key] = cmd.Value
+			result.OK = true
+		}
+	case "scan":
+		result.Pairs = kv.scan(cmd.Key, cmd.End, cmd.Limit)
+	}
+	return result
+}
+
+// Читает значение прямо из автомата, минуя лог. На follower'е или
+// на отставшем лидере значение может быть устаревшим.
+func (kv *KVStateMachine) Get(key string) (value string, found bool) {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+	value, found = kv.data[key]
+	return value, found
+}
+
+func (kv *KVStateMachine) Snapshot() ([]byte, error) {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(kv.data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (kv *KVStateMachine) Restore(data []byte) error {
+	restored := make(map[string]string)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&restored); err != nil {
+		return err
+	}
+	kv.mutex.Lock()
+	kv.data = restored
+	kv.mutex.Unlock()
+	return nil
+}
+
+func (kv *KVStateMachine) scan(start, end string, limit int) []KVPair {
+	keys := []string{}
+	for key := range kv.data {
+		if key >= start && (end == "" || key < end) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	pairs := make([]KVPair, len(keys))
+	for i, key := range keys {
+		pairs[i] = KVPair{Key: key, Value: kv.data[key]}
+	}
+	return pairs
+}
+
+// Клиент хранилища. Все операции, включая чтение, проходят через лог Raft,
+// поэтому история операций линеаризуема. Повторы после смены лидера
+// безопасны: запрос сохраняет Seq и применяется ровно один раз.
+// Клиент не рассчитан на одновременные вызовы из нескольких горутин.
+type KVClient struct {
+	nodes    map[int]*Node
+	clientID int64
+	seq      int64
+	leader   int
+}
+
+func NewKVClient(nodes map[int]*Node, clientID int64) *KVClient {
+	return &KVClient{nodes: nodes, clientID: clientID, leader: -1}
+}
+
+func (c *KVClient) Get(ctx context.Context, key string) (value string, found bool, err error) {
+	res, err := c.do(ctx, KVCommand{Op: "get", Key: key})
+	return res.Value, res.Found, err
+}
+
+func (c *KVClient) Put(ctx context.Context, key, value string) error {
+	_, err := c.do(ctx, KVCommand{Op: "put", Key: key, Value: value})
+	return err
+}
+
+func (c *KVClient) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, KVCommand{Op: "delete", Key: key})
+	return err
+}
+
+// Заменяет значение, только если текущее равно expected
+func (c *KVClient) CompareAndSwap(ctx context.Context, key, expected, value string) (bool, error) {
+	res, err := c.do(ctx, KVCommand{Op: "cas", Key: key, Expected: expected, Value: value})
+	return res.OK, err
+}
+
+// Записывает значение, только если ключа ещё нет (например, для уникальных имён)
+func (c *KVClient) PutIfAbsent(ctx context.Context, key, value string) (bool, error) {
+	res, err := c.do(ctx, KVCommand{Op: "cas", Key: key, ExpectAbsent: true, Value: value})
+	return res.OK, err
+}
+
+// Пары с ключами из [start, end) по возрастанию; пустой end — до конца
+func (c *KVClient) Scan(ctx context.Context, start, end string, limit int) ([]KVPair, error) {
+	res, err := c.do(ctx, KVCommand{Op: "scan", Key: start, End: end, Limit: limit})
+	return res.Pairs, err
+}
+
+// Отправляет команду лидеру, следуя перенаправлениям, пока не истечёт ctx
+func (c *KVClient) do(ctx context.Context, cmd KVCommand) (KVResult, error) {
+	c.seq++
+	req := ClientRequest{ClientID: c.clientID, Seq: c.seq, Command: cmd}
+
+	ids := make([]int, 0, len(c.nodes))
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	next := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return KVResult{}, err
+		}
+		target := c.leader
+		if _, ok := c.nodes[target]; !ok {
+			// Лидер неизвестен — перебираем узлы по кругу
+			target = ids[next%len(ids)]
+			next++
+		}
+
+		attempt, cancel := context.WithTimeout(ctx, time.Second)
+		_, _, result, err := c.nodes[target].propose(attempt, req)
+		cancel()
+
+		var notLeader *NotLeaderError
+		switch {
+		case err == nil:
+			c.leader = target
+			res, _ := result.(KVResult)
+			return res, nil
+		case errors.As(err, &notLeader):
+			c.leader = notLeader.LeaderID
+			if c.leader != target && c.leader != -1 {
+				// Известен новый лидер — идём к нему сразу
+				continue
+			}
+			c.leader = -1
+		default:
+			// Смена лидера или таймаут попытки: повторяем с тем же Seq
+			c.leader = -1
+		}
+		if err := wait(ctx, 10*time.Millisecond); err != nil {
+			return KVResult{}, err
+		}
+	}
+}
+
+// Пауза перед повтором, прерываемая отменой ctx
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Без лидера клиент повторяет запрос, пока не истечёт ctx, и возвращает его ошибку
+func TestKVClientStopsOnContext(t *testing.T) {
+	nodes := map[int]*Node{}
+	for _, id := range []int{1, 2, 3} {
+		node, err := NewNode(id, filter([]int{1, 2, 3}, id), NewMemoryStorage())
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[id] = node
+	}
+	client := NewKVClient(nodes, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.Put(ctx, "k", "v"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Put без лидера: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Put вернулся через %v после истечения ctx", elapsed)
+	}
+}
+
+// Get читает автомат параллельно с Apply. Запускать с -race.
+func TestKVStateMachineConcurrentGet(t *testing.T) {
+	kv := NewKVStateMachine()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			kv.Apply(KVCommand{Op: "put", Key: fmt.Sprint("k", i%10), Value: fmt.Sprint(i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			kv.Get(fmt.Sprint("k", i%10))
+		}
+	}()
+	wg.Wait()
+	if value, found := kv.Get("k9"); !found || value != "999" {
+		t.Errorf("Get(k9) = %q, %v, ожидалось 999", value, found)
+	}
+}
+
+// Запросы SimKVClient идут через сеть симуляции: клиент, отрезанный
+// партицией от кластера, ждёт, пока сеть не восстановится
+func TestSimKVClientUsesNetwork(t *testing.T) {
+	sim := NewSimulation(1, SimOptions{Latency: ConstantLatency(5 * time.Millisecond)})
+	ids := []int{1, 2, 3}
+	cluster := NewSimRaftCluster(sim, ids, func(n *Node) { n.StateMachine = NewKVStateMachine() })
+	sim.RunUntil(func() bool {
+		_, ok := cluster.Leader()
+		return ok
+	}, 10*time.Second)
+
+	client := cluster.NewKVClient(1)
+	sim.Partition(ids, []int{client.Addr()})
+	completed := false
+	client.Do(KVCommand{Op: "put", Key: "k", Value: "v"}, func(KVResult) { completed = true })
+	sim.RunFor(2 * time.Second)
+	if completed {
+		t.Fatal("команда изолированного клиента выполнена")
+	}
+
+	sim.Heal()
+	if !sim.RunUntil(func() bool { return completed }, 2*time.Second) {
+		t.Fatal("команда не выполнена после восстановления сети")
+	}
+	// Запрос и ответ проходят по сети, поэтому занимают не меньше двух задержек
+	start := sim.Elapsed()
+	var elapsed time.Duration
+	client.Do(KVCommand{Op: "get", Key: "k"}, func(res KVResult) {
+		elapsed = sim.Elapsed() - start
+		if res.Value != "v" {
+			t.Errorf("get вернул %q", res.Value)
+		}
+	})
+	sim.RunUntil(func() bool { return elapsed > 0 }, 2*time.Second)
+	if elapsed < 10*time.Millisecond {
+		t.Errorf("команда выполнена за %v, быстрее запроса и ответа по сети", elapsed)
+	}
+}
@@ -0,0 +1,639 @@
+package distributed
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Последовательная спецификация объекта, относительно которой проверяется
+// линеаризуемость истории
+type Model struct {
+	// Начальное состояние
+	Init func() interface{}
+	// Применяет операцию к состоянию и возвращает новое состояние; false,
+	// если из этого состояния операция не могла вернуть output. У операций
+	// без ответа output равен nil — подходит любой результат. Step
+	// не должен изменять переданное состояние.
+	Step func(state, input, output interface{}) (bool, interface{})
+	// Сравнение состояний (nil — оператор ==)
+	Equal func(a, b interface{}) bool
+	// Делит историю на независимые части, которые проверяются по отдельности,
+	// например операции над разными ключами (nil — одна часть)
+	Partition func(history []Operation) [][]Operation
+	// Описания для визуализации (nil — формат %v)
+	DescribeOperation func(input, output interface{}) string
+	DescribeState     func(state interface{}) string
+}
+
+type CheckOutcome int
+
+const (
+	Linearizable CheckOutcome = iota
+	NotLinearizable
+	CheckTimeout // Проверка не уложилась в отведённое время
+)
+
+func (o CheckOutcome) String() string {
+	switch o {
+	case Linearizable:
+		return "линеаризуема"
+	case NotLinearizable:
+		return "не линеаризуема"
+	default:
+		return "не проверена за отведённое время"
+	}
+}
+
+// Результат проверки одной части истории
+type PartitionResult struct {
+	Operations []Operation
+	Outcome    CheckOutcome
+	// Самая длинная найденная линеаризация: номера операций из Operations
+	// в порядке линеаризации. У линеаризуемой части — все операции.
+	Longest []int
+}
+
+type CheckResult struct {
+	Outcome    CheckOutcome
+	Partitions []PartitionResult
+	model      Model
+}
+
+// Проверяет, линеаризуема ли история относительно model: можно ли
+// упорядочить операции так, чтобы каждая выполнилась мгновенно между своими
+// Call и Return, а ответы совпали с последовательной спецификацией.
+// Перебор с возвратами Wing–Gong с кэшем пройденных состояний (Lowe),
+// как в Porcupine. В худшем случае время экспоненциально, поэтому
+// проверка прерывается через timeout (0 — без ограничения).
+func CheckLinearizability(model Model, history []Operation, timeout time.Duration) *CheckResult {
+	parts := [][]Operation{history}
+	if model.Partition != nil {
+		parts = model.Partition(history)
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	result := &CheckResult{Outcome: Linearizable, model: model}
+	for _, ops := range parts {
+		outcome, longest := checkPartition(model, ops, deadline)
+		result.Partitions = append(result.Partitions, PartitionResult{Operations: ops, Outcome: outcome, Longest: longest})
+		if outcome == NotLinearizable || (outcome == CheckTimeout && result.Outcome == Linearizable) {
+			result.Outcome = outcome
+		}
+	}
+	return result
+}
+
+// Событие истории в двусвязном списке: вызов или ответ операции id
+type linEntry struct {
+	id         int
+	time       int64
+	input      interface{}
+	output     interface{}
+	match      *linEntry // У вызова — его ответ, у ответа — nil
+	prev, next *linEntry
+}
+
+// Исключает из списка вызов e вместе с его ответом
+func (e *linEntry) lift() {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+// Возвращает в список вызов, исключённый lift
+func (e *linEntry) unlift() {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int)   { b[i/64] |= 1 << (i % 64) }
+func (b bitset) clear(i int) { b[i/64] &^= 1 << (i % 64) }
+
+func (b bitset) clone() bitset {
+	return append(bitset(nil), b...)
+}
+
+func (b bitset) equal(other bitset) bool {
+	for i := range b {
+		if b[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) hash() uint64 {
+	h := uint64(14695981039346656037) // FNV-1a
+	for _, word := range b {
+		h ^= word
+		h *= 1099511628211
+	}
+	return h
+}
+
+// Пройденная конфигурация: множество линеаризованных операций и состояние после них
+type linCacheEntry struct {
+	linearized bitset
+	state      interface{}
+}
+
+func checkPartition(model Model, ops []Operation, deadline time.Time) (CheckOutcome, []int) {
+	equal := model.Equal
+	if equal == nil {
+		equal = func(a, b interface{}) bool { return a == b }
+	}
+
+	// События по времени; вызов раньше ответа с тем же временем,
+	// то есть такие операции считаются одновременными
+	events := make([]*linEntry, 0, 2*len(ops))
+	for id, op := range ops {
+		ret := &linEntry{id: id, time: math.MaxInt64, output: op.Output}
+		if !op.Pending() {
+			ret.time = op.Return.UnixNano()
+		}
+		call := &linEntry{id: id, time: op.Call.UnixNano(), input: op.Input, match: ret}
+		events = append(events, call, ret)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time != events[j].time {
+			return events[i].time < events[j].time
+		}
+		return events[i].match != nil && events[j].match == nil
+	})
+	head := &linEntry{}
+	prev := head
+	for _, e := range events {
+		prev.next, e.prev = e, prev
+		prev = e
+	}
+
+	type call struct {
+		entry *linEntry
+		state interface{} // Состояние до операции
+	}
+	var calls []call
+	var longest []int
+	linearized := newBitset(len(ops))
+	cache := make(map[uint64][]linCacheEntry)
+	state := model.Init()
+
+	entry := head.next
+	for steps := 0; head.next != nil; steps++ {
+		if steps%1024 == 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			return CheckTimeout, longest
+		}
+
+		if entry.match != nil {
+			// Пробуем линеаризовать эту операцию следующей
+			ok, next := model.Step(state, entry.input, entry.match.output)
+			if ok {
+				candidate := linearized.clone()
+				candidate.set(entry.id)
+				seen := false
+				for _, c := range cache[candidate.hash()] {
+					if c.linearized.equal(candidate) && equal(c.state, next) {
+						seen = true
+						break
+					}
+				}
+				if !seen {
+					cache[candidate.hash()] = append(cache[candidate.hash()], linCacheEntry{candidate, next})
+					calls = append(calls, call{entry, state})
+					if len(calls) > len(longest) {
+						longest = longest[:0]
+						for _, c := range calls {
+							longest = append(longest, c.entry.id)
+						}
+					}
+					state = next
+					linearized.set(entry.id)
+					entry.lift()
+					entry = head.next
+					continue
+				}
+			}
+			entry = entry.next
+			continue
+		}
+
+		// Дошли до ответа операции, которую не удалось линеаризовать:
+		// отменяем последнюю линеаризованную и пробуем следующую за ней
+		if len(calls) == 0 {
+			return NotLinearizable, longest
+		}
+		top := calls[len(calls)-1]
+		calls = calls[:len(calls)-1]
+		state = top.state
+		linearized.clear(top.entry.id)
+		top.entry.unlift()
+		entry = top.entry.next
+	}
+	return Linearizable, longest
+}
+
+// Ширина временной оси в визуализации, в символах
+const timelineWidth = 72
+
+// Сколько последних линеаризованных операций показывает визуализация
+const visualizeTail = 8
+
+// Печатает части истории, которые не удалось линеаризовать. Для каждой —
+// последние операции самой длинной найденной линеаризации с состоянием
+// после каждой, операции, которые можно было бы поставить следующими,
+// но ни одна не подходит, и временную диаграмму этих операций по клиентам.
+// На диаграмме [#3====] — операция 3, вошедшая в линеаризацию,
+// [#5----] — не вошедшая, | — мгновенная операция, > — операция без ответа.
+func (r *CheckResult) Visualize(w io.Writer) error {
+	describeOp := r.model.DescribeOperation
+	if describeOp == nil {
+		describeOp = func(input, output interface{}) string { return fmt.Sprintf("%v → %v", input, output) }
+	}
+	describeState := r.model.DescribeState
+	if describeState == nil {
+		describeState = func(state interface{}) string { return fmt.Sprintf("%v", state) }
+	}
+
+	var b strings.Builder
+	if r.Outcome == Linearizable {
+		fmt.Fprintf(&b, "История линеаризуема, проверено частей: %d\n", len(r.Partitions))
+	}
+	for i, part := range r.Partitions {
+		if part.Outcome == Linearizable {
+			continue
+		}
+		ops := part.Operations
+		fmt.Fprintf(&b, "Часть %d из %d: %s (%d операций, линеаризовано %d)\n",
+			i+1, len(r.Partitions), part.Outcome, len(ops), len(part.Longest))
+
+		origin := ops[0].Call
+		linearized := make(map[int]bool)
+		for _, id := range part.Longest {
+			linearized[id] = true
+		}
+		tail := part.Longest[max(0, len(part.Longest)-visualizeTail):]
+		frontier := nextCandidates(ops, linearized)
+
+		b.WriteString("Линеаризация")
+		if len(tail) < len(part.Longest) {
+			fmt.Fprintf(&b, " (последние %d)", len(tail))
+		}
+		b.WriteString(":\n")
+		state := r.model.Init()
+		for k, id := range part.Longest {
+			op := ops[id]
+			_, state = r.model.Step(state, op.Input, op.Output)
+			if k >= len(part.Longest)-len(tail) {
+				fmt.Fprintf(&b, "  %4d. %-5s клиент %-3d %-28s %s  состояние: %s\n", k+1, fmt.Sprintf("#%d", id+1),
+					op.ClientID, describeOp(op.Input, op.Output), formatSpan(origin, op), describeState(state))
+			}
+		}
+		if part.Outcome == NotLinearizable {
+			b.WriteString("Ни одну из этих операций нельзя поставить следующей:\n")
+			for _, id := range frontier {
+				op := ops[id]
+				fmt.Fprintf(&b, "        %-5s клиент %-3d %-28s %s\n", fmt.Sprintf("#%d", id+1),
+					op.ClientID, describeOp(op.Input, op.Output), formatSpan(origin, op))
+			}
+		}
+		writeTimeline(&b, ops, append(append([]int(nil), tail...), frontier...), linearized, origin)
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Нелинеаризованные операции, которые по времени могли бы идти следующими:
+// вызванные до самого раннего ответа среди нелинеаризованных
+func nextCandidates(ops []Operation, linearized map[int]bool) []int {
+	var earliest time.Time
+	for id, op := range ops {
+		if !linearized[id] && !op.Pending() && (earliest.IsZero() || op.Return.Before(earliest)) {
+			earliest = op.Return
+		}
+	}
+	var ids []int
+	for id, op := range ops {
+		if !linearized[id] && (earliest.IsZero() || !op.Call.After(earliest)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Интервал операции в миллисекундах от origin
+func formatSpan(origin time.Time, op Operation) string {
+	if op.Pending() {
+		return fmt.Sprintf("[%10.3fms, без ответа]", milliseconds(op.Call.Sub(origin)))
+	}
+	return fmt.Sprintf("[%10.3fms, %10.3fms]", milliseconds(op.Call.Sub(origin)), milliseconds(op.Return.Sub(origin)))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Рисует операции ids на общей временной оси: по строке на клиента,
+// а если операции клиента пересекаются — по несколько строк. Ось
+// охватывает только эти операции; операции без ответа обрезаются по её концу.
+func writeTimeline(b *strings.Builder, ops []Operation, ids []int, linearized map[int]bool, origin time.Time) {
+	if len(ids) == 0 {
+		return
+	}
+	start, end := ops[ids[0]].Call, ops[ids[0]].Call
+	for _, id := range ids {
+		op := ops[id]
+		if op.Call.Before(start) {
+			start = op.Call
+		}
+		if op.Call.After(end) {
+			end = op.Call
+		}
+		if !op.Pending() && op.Return.After(end) {
+			end = op.Return
+		}
+	}
+	span := float64(end.Sub(start))
+	column := func(t time.Time) int {
+		if span == 0 {
+			return 0
+		}
+		return int(float64(t.Sub(start)) / span * (timelineWidth - 1))
+	}
+
+	byClient := make(map[int][]int)
+	var clients []int
+	for _, id := range ids {
+		client := ops[id].ClientID
+		if _, ok := byClient[client]; !ok {
+			clients = append(clients, client)
+		}
+		byClient[client] = append(byClient[client], id)
+	}
+	sort.Ints(clients)
+
+	from := fmt.Sprintf("%.3fms", milliseconds(start.Sub(origin)))
+	fmt.Fprintf(b, "%-12s%s%*s\n", "", from, timelineWidth-len(from), fmt.Sprintf("%.3fms", milliseconds(end.Sub(origin))))
+	for _, client := range clients {
+		ids := byClient[client]
+		sort.SliceStable(ids, func(i, j int) bool { return ops[ids[i]].Call.Before(ops[ids[j]].Call) })
+
+		var lanes [][]rune
+		var laneEnd []int
+		for _, id := range ids {
+			op := ops[id]
+			from, to := column(op.Call), timelineWidth-1
+			if !op.Pending() {
+				to = column(op.Return)
+			}
+			lane := -1
+			for l := range lanes {
+				if laneEnd[l] < from {
+					lane = l
+					break
+				}
+			}
+			if lane == -1 {
+				lanes = append(lanes, []rune(strings.Repeat(" ", timelineWidth)))
+				laneEnd = append(laneEnd, -1)
+				lane = len(lanes) - 1
+			}
+			drawOperation(lanes[lane], from, to, id, op.Pending(), linearized[id])
+			laneEnd[lane] = to
+		}
+		for l, lane := range lanes {
+			label := ""
+			if l == 0 {
+				label = fmt.Sprintf("клиент %d", client)
+			}
+			fmt.Fprintf(b, "%-12s%s\n", label, strings.TrimRight(string(lane), " "))
+		}
+	}
+}
+
+func drawOperation(lane []rune, from, to, id int, pending, linearized bool) {
+	if from == to {
+		lane[from] = '|'
+		return
+	}
+	fill := '-'
+	if linearized {
+		fill = '='
+	}
+	for c := from + 1; c < to; c++ {
+		lane[c] = fill
+	}
+	lane[from], lane[to] = '[', ']'
+	if pending {
+		lane[to] = '>'
+	}
+	if label := fmt.Sprintf("#%d", id+1); len(label) < to-from {
+		copy(lane[from+1:], []rune(label))
+	}
+}
+
+// Операция над регистром: "read", "write" или "cas" — записать Value,
+// если текущее значение равно Expected
+type RegisterInput struct {
+	Op       string
+	Value    string
+	Expected string
+}
+
+// Ответ регистра: для "read" — прочитанное значение, для "cas" — удалась ли замена
+type RegisterOutput struct {
+	Value string
+	OK    bool
+}
+
+// Модель одного регистра со строковым значением, изначально пустым
+func RegisterModel() Model {
+	return Model{
+		Init: func() interface{} { return "" },
+		Step: func(state, input, output interface{}) (bool, interface{}) {
+			value := state.(string)
+			in, ok := input.(RegisterInput)
+			if !ok {
+				return false, state
+			}
+			out, known := output.(RegisterOutput)
+			switch in.Op {
+			case "read":
+				return !known || out.Value == value, value
+			case "write":
+				return true, in.Value
+			case "cas":
+				swapped := value == in.Expected
+				if known && out.OK != swapped {
+					return false, state
+				}
+				if swapped {
+					return true, in.Value
+				}
+				return true, value
+			}
+			return false, state
+		},
+		DescribeOperation: func(input, output interface{}) string {
+			in := input.(RegisterInput)
+			out, known := output.(RegisterOutput)
+			switch {
+			case in.Op == "read" && known:
+				return fmt.Sprintf("read → %q", out.Value)
+			case in.Op == "cas" && known:
+				return fmt.Sprintf("cas %q→%q → %v", in.Expected, in.Value, out.OK)
+			case in.Op == "cas":
+				return fmt.Sprintf("cas %q→%q → ?", in.Expected, in.Value)
+			case in.Op == "write":
+				return fmt.Sprintf("write %q", in.Value)
+			}
+			return in.Op + " → ?"
+		},
+		DescribeState: func(state interface{}) string { return fmt.Sprintf("%q", state) },
+	}
+}
+
+// Модель хранилища ключ-значение для истории команд KVCommand с ответами
+// KVResult, как их возвращает KVStateMachine. Если в истории нет "scan",
+// она делится по ключам и каждый ключ проверяется отдельно.
+func KVModel() Model {
+	return Model{
+		Init: func() interface{} { return map[string]string{} },
+		Step: func(state, input, output interface{}) (bool, interface{}) {
+			cmd, ok := input.(KVCommand)
+			if !ok {
+				return false, state
+			}
+			data := state.(map[string]string)
+			if cmd.Op != "get" && cmd.Op != "scan" {
+				// Состояния хранятся в кэше проверки, поэтому изменяем копию
+				copied := make(map[string]string, len(data)+1)
+				for k, v := range data {
+					copied[k] = v
+				}
+				data = copied
+			}
+			kv := &KVStateMachine{data: data}
+			expected := kv.Apply(cmd).(KVResult)
+			if actual, known := output.(KVResult); known && !equalKVResult(expected, actual) {
+				return false, state
+			}
+			return true, kv.data
+		},
+		Equal: func(a, b interface{}) bool {
+			x, y := a.(map[string]string), b.(map[string]string)
+			if len(x) != len(y) {
+				return false
+			}
+			for k, v := range x {
+				if other, ok := y[k]; !ok || other != v {
+					return false
+				}
+			}
+			return true
+		},
+		Partition: func(history []Operation) [][]Operation {
+			byKey := make(map[string][]Operation)
+			var keys []string
+			for _, op := range history {
+				cmd, _ := op.Input.(KVCommand)
+				if cmd.Op == "scan" {
+					return [][]Operation{history}
+				}
+				if _, ok := byKey[cmd.Key]; !ok {
+					keys = append(keys, cmd.Key)
+				}
+				byKey[cmd.Key] = append(byKey[cmd.Key], op)
+			}
+			sort.Strings(keys)
+			parts := make([][]Operation, len(keys))
+			for i, key := range keys {
+				parts[i] = byKey[key]
+			}
+			return parts
+		},
+		DescribeOperation: describeKVOperation,
+		DescribeState: func(state interface{}) string {
+			data := state.(map[string]string)
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k + "=" + data[k]
+			}
+			return "{" + strings.Join(pairs, ", ") + "}"
+		},
+	}
+}
+
+// Сравнивает результаты без различия между nil и пустым Pairs
+// (после gob пустой срез превращается в nil)
+func equalKVResult(a, b KVResult) bool {
+	if a.Value != b.Value || a.Found != b.Found || a.OK != b.OK || len(a.Pairs) != len(b.Pairs) {
+		return false
+	}
+	for i := range a.Pairs {
+		if a.Pairs[i] != b.Pairs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func describeKVOperation(input, output interface{}) string {
+	cmd := input.(KVCommand)
+	res, known := output.(KVResult)
+	var op string
+	switch cmd.Op {
+	case "get", "delete":
+		op = cmd.Op + " " + cmd.Key
+	case "put":
+		op = fmt.Sprintf("put %s=%s", cmd.Key, cmd.Value)
+	case "cas":
+		expected := cmd.Expected
+		if cmd.ExpectAbsent {
+			expected = "∅"
+		}
+		op = fmt.Sprintf("cas %s %s→%s", cmd.Key, expected, cmd.Value)
+	case "scan":
+		op = fmt.Sprintf("scan [%s, %s)", cmd.Key, cmd.End)
+	default:
+		op = cmd.Op
+	}
+
+	switch {
+	case !known:
+		return op + " → ?"
+	case cmd.Op == "cas":
+		return fmt.Sprintf("%s → %v", op, res.OK)
+	case cmd.Op == "scan":
+		return fmt.Sprintf("%s → %d пар", op, len(res.Pairs))
+	case !res.Found:
+		return op + " → ∅"
+	}
+	return op + " → " + res.Value
+}
@@ -0,0 +1,285 @@
+package distributed
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Упавший случайный сценарий воспроизводится по seed:
+//
+//	go test ./distributed -run TestKVLinearizableUnderFaults -seed 7 -v
+var seedFlag = flag.Int64("seed", 0, "прогнать случайные сценарии только с этим seed")
+
+// Seed для случайных сценариев: 1..runs или только -seed
+func testSeeds(runs int) []int64 {
+	if *seedFlag != 0 {
+		return []int64{*seedFlag}
+	}
+	if testing.Short() {
+		runs = min(runs, 2)
+	}
+	seeds := make([]int64, runs)
+	for i := range seeds {
+		seeds[i] = int64(i + 1)
+	}
+	return seeds
+}
+
+var historyOrigin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Операция клиента между call и ret миллисекундами; ret < 0 — ответа нет
+func historyOp(client, call, ret int, input, output interface{}) Operation {
+	op := Operation{ClientID: client, Input: input, Call: historyOrigin.Add(time.Duration(call) * time.Millisecond)}
+	if ret >= 0 {
+		op.Output = output
+		op.Return = historyOrigin.Add(time.Duration(ret) * time.Millisecond)
+	}
+	return op
+}
+
+func write(value string) RegisterInput { return RegisterInput{Op: "write", Value: value} }
+func read() RegisterInput              { return RegisterInput{Op: "read"} }
+func value(v string) RegisterOutput    { return RegisterOutput{Value: v} }
+
+func TestRegisterLinearizable(t *testing.T) {
+	history := []Operation{
+		historyOp(1, 0, 10, write("1"), RegisterOutput{}),
+		// Чтение, пересекающееся с записью, может увидеть и старое, и новое значение
+		historyOp(2, 5, 15, read(), value("")),
+		historyOp(3, 6, 16, read(), value("1")),
+		historyOp(1, 20, 30, RegisterInput{Op: "cas", Expected: "1", Value: "2"}, RegisterOutput{OK: true}),
+		historyOp(2, 25, 35, RegisterInput{Op: "cas", Expected: "1", Value: "3"}, RegisterOutput{OK: false}),
+		historyOp(3, 40, 50, read(), value("2")),
+	}
+	result := CheckLinearizability(RegisterModel(), history, 0)
+	if result.Outcome != Linearizable {
+		t.Fatalf("история %s, ожидалась линеаризуемая", result.Outcome)
+	}
+	if got := len(result.Partitions[0].Longest); got != len(history) {
+		t.Errorf("линеаризация из %d операций, ожидалось %d", got, len(history))
+	}
+}
+
+// Чтение после завершённой записи вернуло старое значение
+func TestRegisterStaleRead(t *testing.T) {
+	history := []Operation{
+		historyOp(1, 0, 10, write("1"), RegisterOutput{}),
+		historyOp(2, 20, 30, read(), value("")),
+	}
+	result := CheckLinearizability(RegisterModel(), history, 0)
+	if result.Outcome != NotLinearizable {
+		t.Fatalf("история %s, ожидалась нелинеаризуемая", result.Outcome)
+	}
+	var out bytes.Buffer
+	if err := result.Visualize(&out); err != nil || out.Len() == 0 {
+		t.Errorf("Visualize: %v, %d байт", err, out.Len())
+	}
+}
+
+// Операция без ответа могла выполниться в любой момент после вызова или не выполниться
+func TestPendingOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []Operation
+		want    CheckOutcome
+	}{
+		{"не выполнилась", []Operation{
+			historyOp(1, 0, -1, write("1"), nil),
+			historyOp(2, 10, 20, read(), value("")),
+			historyOp(2, 100, 110, read(), value("")),
+		}, Linearizable},
+		{"выполнилась позже", []Operation{
+			historyOp(1, 0, -1, write("1"), nil),
+			historyOp(2, 10, 20, read(), value("")),
+			historyOp(2, 100, 110, read(), value("1")),
+		}, Linearizable},
+		{"не могла выполниться до вызова", []Operation{
+			historyOp(2, 0, 10, read(), value("1")),
+			historyOp(1, 20, -1, write("1"), nil),
+		}, NotLinearizable},
+		{"выполнилась один раз", []Operation{
+			historyOp(1, 0, -1, write("1"), nil),
+			historyOp(2, 10, 20, read(), value("1")),
+			historyOp(2, 30, 40, write("2"), RegisterOutput{}),
+			historyOp(2, 50, 60, read(), value("1")),
+		}, NotLinearizable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckLinearizability(RegisterModel(), tt.history, 0).Outcome; got != tt.want {
+				t.Errorf("история %s, ожидалась %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// KVModel проверяет ключи по отдельности, пока в истории нет scan
+func TestKVModelPartition(t *testing.T) {
+	put := func(key, value string) KVCommand { return KVCommand{Op: "put", Key: key, Value: value} }
+	get := func(key string) KVCommand { return KVCommand{Op: "get", Key: key} }
+	history := []Operation{
+		historyOp(1, 0, 10, put("a", "1"), KVResult{}),
+		historyOp(2, 0, 10, put("b", "1"), KVResult{}),
+		historyOp(1, 20, 30, get("a"), KVResult{Value: "1", Found: true}),
+		// Устаревшее чтение только по ключу b
+		historyOp(2, 20, 30, get("b"), KVResult{}),
+	}
+	result := CheckLinearizability(KVModel(), history, 0)
+	if result.Outcome != NotLinearizable {
+		t.Fatalf("история %s, ожидалась нелинеаризуемая", result.Outcome)
+	}
+	if len(result.Partitions) != 2 {
+		t.Fatalf("частей %d, ожидалось 2", len(result.Partitions))
+	}
+	for i, want := range []CheckOutcome{Linearizable, NotLinearizable} {
+		if got := result.Partitions[i].Outcome; got != want {
+			t.Errorf("ключ %s: %s, ожидалось %s", result.Partitions[i].Operations[0].Input.(KVCommand).Key, got, want)
+		}
+	}
+
+	// scan читает несколько ключей, поэтому история проверяется целиком
+	history = append(history, historyOp(3, 40, 50, KVCommand{Op: "scan"}, KVResult{}))
+	if parts := len(CheckLinearizability(KVModel(), history, 0).Partitions); parts != 1 {
+		t.Errorf("со scan частей %d, ожидалась 1", parts)
+	}
+}
+
+// Хранилище ключ-значение поверх Raft под нагрузкой, с потерями сообщений,
+// партициями и падениями узлов даёт линеаризуемую историю
+func TestKVLinearizableUnderFaults(t *testing.T) {
+	for _, seed := range testSeeds(5) {
+		t.Run(fmt.Sprint("seed=", seed), func(t *testing.T) {
+			history := runKVWorkload(seed, 10*time.Second, 4, 3, false)
+			result := CheckLinearizability(KVModel(), history, 10*time.Second)
+			if result.Outcome != Linearizable {
+				var out bytes.Buffer
+				result.Visualize(&out)
+				t.Fatalf("история из %d операций %s:\n%s", len(history), result.Outcome, out.String())
+			}
+		})
+	}
+}
+
+// Чтения из автомата случайного узла в обход лога — ошибка реализации,
+// которую проверка должна находить
+func TestKVStaleReadsDetected(t *testing.T) {
+	for _, seed := range testSeeds(5) {
+		history := runKVWorkload(seed, 10*time.Second, 4, 3, true)
+		if CheckLinearizability(KVModel(), history, 10*time.Second).Outcome == NotLinearizable {
+			return
+		}
+	}
+	t.Fatal("устаревшие чтения не найдены ни в одном прогоне")
+}
+
+// Клиенты выполняют случайные операции, пока кластер каждые полсекунды
+// партиционируется, падает и восстанавливается. С staleReads чтения
+// обслуживаются локально случайным узлом.
+func runKVWorkload(seed int64, duration time.Duration, clients, keys int, staleReads bool) []Operation {
+	sim := NewSimulation(seed, SimOptions{
+		Latency:       ExponentialLatency(5 * time.Millisecond),
+		DropRate:      0.05,
+		DuplicateRate: 0.02,
+		ReorderRate:   0.1,
+	})
+	ids := []int{1, 2, 3, 4, 5}
+	cluster := NewSimRaftCluster(sim, ids, func(n *Node) {
+		n.StateMachine = NewKVStateMachine()
+		n.SnapshotThreshold = 20
+	})
+	history := NewHistory(sim)
+	rnd := sim.Rand()
+	running := true
+
+	var addrs []int
+	for c := 1; c <= clients; c++ {
+		client := cluster.NewKVClient(int64(c))
+		addrs = append(addrs, client.Addr())
+		id, written := c, 0
+		var next func()
+		next = func() {
+			if !running {
+				return
+			}
+			cmd := KVCommand{Key: fmt.Sprintf("k%d", rnd.Intn(keys))}
+			switch p := rnd.Float64(); {
+			case p < 0.4:
+				cmd.Op = "get"
+			case p < 0.7:
+				written++
+				cmd.Op, cmd.Value = "put", fmt.Sprintf("%d.%d", id, written)
+			case p < 0.9:
+				written++
+				cmd.Op, cmd.Value = "cas", fmt.Sprintf("%d.%d", id, written)
+				cmd.Expected = fmt.Sprintf("%d.%d", 1+rnd.Intn(clients), 1+rnd.Intn(written))
+			default:
+				cmd.Op = "delete"
+			}
+
+			op := history.Invoke(id, cmd)
+			if staleReads && cmd.Op == "get" {
+				node := cluster.Nodes[ids[rnd.Intn(len(ids))]]
+				value, found := node.StateMachine.(*KVStateMachine).Get(cmd.Key)
+				history.Complete(op, KVResult{Value: value, Found: found})
+				sim.AfterFunc(time.Duration(rnd.Intn(50))*time.Millisecond, next)
+				return
+			}
+			client.Do(cmd, func(res KVResult) {
+				history.Complete(op, res)
+				sim.AfterFunc(time.Duration(rnd.Intn(50))*time.Millisecond, next)
+			})
+		}
+		sim.AfterFunc(time.Duration(rnd.Intn(50))*time.Millisecond, next)
+	}
+
+	for sim.Elapsed() < duration {
+		randomFault(sim, ids, addrs)
+		sim.RunFor(500 * time.Millisecond)
+	}
+
+	// Восстанавливаем кластер, чтобы зависшие операции успели завершиться
+	running = false
+	sim.Heal()
+	for _, id := range ids {
+		sim.Restart(id)
+	}
+	sim.RunFor(5 * time.Second)
+	return history.Operations()
+}
+
+// Случайный сбой или восстановление: партиция, исцеление сети,
+// падение или перезапуск узла, или ничего. Клиенты с адресами clients
+// при партиции попадают в случайные части вместе с узлами.
+func randomFault(sim *Simulation, ids, clients []int) {
+	rnd := sim.Rand()
+	switch rnd.Intn(5) {
+	case 0:
+		perm := rnd.Perm(len(ids))
+		cut := 1 + rnd.Intn(len(ids)-1)
+		var left, right []int
+		for i, p := range perm {
+			if i < cut {
+				left = append(left, ids[p])
+			} else {
+				right = append(right, ids[p])
+			}
+		}
+		for _, addr := range clients {
+			if rnd.Intn(2) == 0 {
+				left = append(left, addr)
+			} else {
+				right = append(right, addr)
+			}
+		}
+		sim.Partition(left, right)
+	case 1:
+		sim.Heal()
+	case 2:
+		sim.Crash(ids[rnd.Intn(len(ids))])
+	case 3:
+		sim.Restart(ids[rnd.Intn(len(ids))])
+	}
+}
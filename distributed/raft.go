@@ -1,8 +1,13 @@
 package distributed
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,20 +19,53 @@ const (
 	Follower Role = iota
 	Candidate
 	Leader
+	PreCandidate // Выясняет через PreVote, может ли победить на выборах
 )
 
+// Период рассылки AppendEntries лидером
+const heartbeatInterval = 50 * time.Millisecond
+
+// Наименьший таймаут выборов; таймаут выбирается случайно из [min, 2·min)
+const electionTimeoutMin = 150 * time.Millisecond
+
+// Период, за который лидеру с CheckQuorum должно ответить большинство:
+// наибольший таймаут выборов
+const checkQuorumInterval = 2 * electionTimeoutMin
+
+// Наибольшее число записей в одном AppendEntries
+const maxEntriesPerMessage = 64
+
+// Размер части снимка в одном InstallSnapshot
+const snapshotChunkSize = 16 * 1024
+
 // Тип сообщения
 type MessageRaft struct {
-	Term         int
-	FromID       int
-	ToID         int
-	Type         string // "RequestVote", "RequestVoteReply", "AppendEntries", "AppendEntriesReply"
+	Term   int
+	FromID int
+	ToID   int
+	// "PreVote", "PreVoteReply", "RequestVote", "RequestVoteReply", "AppendEntries",
+	// "AppendEntriesReply", "InstallSnapshot", "InstallSnapshotReply"
+	Type         string
 	LastLogIndex int
 	LastLogTerm  int
 	VoteGranted  bool
 	Success      bool
+	PrevLogIndex int
+	PrevLogTerm  int
 	Entries      []LogEntry
 	LeaderCommit int
+	// Ответ на AppendEntries: индекс последней совпавшей записи при успехе,
+	// при отказе — подсказка, с какого индекса повторить
+	MatchIndex    int
+	ConflictIndex int
+	ConflictTerm  int
+	// InstallSnapshot: часть Data снимка, покрывающего записи до SnapshotIndex,
+	// начиная с байта Offset. В ответе Offset — сколько байт follower уже принял.
+	SnapshotIndex int
+	SnapshotTerm  int
+	Offset        int
+	Data          []byte
+	Done          bool
 }
 
 // Запись лога
@@ -36,6 +74,79 @@ type LogEntry struct {
 	Command interface{}
 }
 
+// Команда клиента. Повтор запроса с теми же ClientID и Seq (например, после
+// смены лидера) не применяется второй раз: Propose вернёт индекс исходной записи.
+// Seq каждого клиента должен возрастать.
+type ClientRequest struct {
+	ClientID int64
+	Seq      int64
+	Command  interface{}
+}
+
+// Последний применённый запрос клиента
+type session struct {
+	Seq    int64
+	Index  int
+	Term   int
+	Result interface{}
+}
+
+// Узел не является лидером. LeaderID — известный ему лидер или -1.
+type NotLeaderError struct {
+	LeaderID int
+}
+
+func (e *NotLeaderError) Error() string {
+	return fmt.Sprintf("raft: not the leader, leader is %d", e.LeaderID)
+}
+
+var (
+	// Запись заменена записью другого лидера до коммита; запрос нужно повторить
+	ErrLeadershipLost = errors.New("raft: leadership lost before the entry was committed")
+	// Seq меньше последнего применённого для этого клиента
+	ErrStaleRequest = errors.New("raft: stale client request")
+)
+
+// Результат применения записи для ожидающего Propose
+type applyResult struct {
+	index, term int // Индекс и срок, под которыми команда применена
+	result      interface{}
+	err         error // ErrLeadershipLost, если под индексом закоммичена другая запись
+}
+
+// Ожидающий применения записи index из срока term; notify вызывается под mutex узла
+type waiter struct {
+	term   int
+	notify func(applyResult)
+}
+
+// Закоммиченная запись, которую узел передаёт приложению.
+// Индексы записей лога начинаются с 1. Если Snapshot не nil, узел
+// получил снимок от лидера: это состояние автомата после записи Index,
+// которое заменяет всё применённое ранее.
+type ApplyMsg struct {
+	Index    int
+	Term     int
+	Command  interface{}
+	Snapshot []byte
+}
+
+// Снимок узла: состояние автомата и сессии клиентов, без которых
+// повторный запрос после восстановления из снимка применился бы дважды
+type snapshotData struct {
+	Sessions map[int64]session
+	State    []byte
+}
+
+// Ошибки не кодируются gob, поэтому в снимке хранится их текст
+type errorResult struct {
+	Message string
+}
+
+func (e errorResult) Error() string {
+	return e.Message
+}
+
 // Структура узла
 type Node struct {
 	ID          int
@@ -44,14 +155,55 @@ type Node struct {
 	State       Role
 	CurrentTerm int
 	VotedFor    int
-	Log         []LogEntry
+	Log         []LogEntry // Log[i-1] — запись с индексом i
 	CommitIndex int
 	LastApplied int
 	NextIndex   map[int]int
 	MatchIndex  map[int]int
-	Inbox       chan MessageRaft
-	countVotes  int
-	mutex       sync.Mutex
+	// Закоммиченные записи в порядке индексов. Если nil, записи не передаются.
+	// Пустые записи, которые лидер добавляет в начале срока, сюда не попадают,
+	// а ClientRequest передаётся без обёртки — только Command.
+	ApplyCh chan ApplyMsg
+	// Автомат, к которому применяются закоммиченные команды. Применение идёт
+	// синхронно под mutex узла, поэтому Apply должен быть быстрым.
+	StateMachine StateMachine
+	// Делать снимок StateMachine и уплотнять лог, когда после предыдущего
+	// снимка применено столько записей (0 — не делать)
+	SnapshotThreshold int
+	// Перед выборами спрашивать, проголосовали бы за узел, не увеличивая
+	// срок. Узел, отрезанный от большинства, тогда не наращивает срок
+	// и не сбивает лидера, вернувшись в сеть. Включено в NewNode.
+	PreVote bool
+	// Лидер уходит в follower'ы, если за checkQuorumInterval ему не ответило
+	// большинство: клиенты не ждут лидера, отрезанного от кластера.
+	// Включено в NewNode.
+	CheckQuorum bool
+	// Куда писать журнал событий узла (nil — стандартный вывод)
+	Logger        func(format string, args ...interface{})
+	votes         map[int]bool // Узлы, проголосовавшие за нас в текущем сроке
+	preVotes      map[int]bool // Узлы, готовые голосовать за нас в следующем сроке
+	active        map[int]bool // У лидера: узлы, ответившие с последней проверки кворума
+	leaderContact time.Time    // Когда узел последний раз получил сообщение лидера
+	mutex         sync.Mutex
+	applyCond     *sync.Cond
+	applyQueue    []ApplyMsg
+	sessions      map[int64]session
+	waiters       map[int][]waiter
+	transport     Transport[MessageRaft] // Задаётся в Run
+	clock         Clock
+	rand          *rand.Rand
+	electionTimer Timer
+	electionGen   int  // Поколение таймера выборов: сработавший старый таймер ничего не делает
+	stopped       bool // Run завершился
+	storage       RaftStorage
+	// Последняя запись, вошедшая в снимок: Log[0] — запись snapshotIndex+1
+	snapshotIndex int
+	snapshotTerm  int
+	snapshot      []byte
+	// Принимаемый по частям снимок лидера
+	incoming                    []byte
+	incomingIndex, incomingTerm int
+	snapshotOffset              map[int]int // У лидера: сколько байт снимка принял follower
 }
 
 // Фильтрует список узлов, исключая текущий
@@ -65,183 +217,981 @@ func filter(ids []int, exclude int) []int {
 	return peers
 }
 
-// Инициализация узлов
+// Инициализация узлов с хранилищем в памяти
 func InitializeNodes(ids []int) map[int]*Node {
 	result := make(map[int]*Node)
 	for _, id := range ids {
-		result[id] = &Node{
-			ID:         id,
-			State:      Follower,
-			LeaderID:   -1,
-			Peers:      filter(ids, id),
-			VotedFor:   -1,
-			Inbox:      make(chan MessageRaft, 10),
-			NextIndex:  make(map[int]int),
-			MatchIndex: make(map[int]int),
-		}
+		// Загрузка из пустого MemoryStorage не может завершиться ошибкой
+		result[id], _ = NewNode(id, filter(ids, id), NewMemoryStorage())
 	}
 	return result
 }
 
-func (n *Node) Run(wg *sync.WaitGroup, nodes map[int]*Node) {
+// Создаёт узел и восстанавливает срок, голос, снимок и лог из хранилища.
+// Перезапуск узла — это NewNode с тем же хранилищем: Run восстановит
+// StateMachine из снимка, а записи после него будут заново применены,
+// когда узел узнает CommitIndex от лидера. Поэтому StateMachine нового
+// узла должен быть пустым.
+func NewNode(id int, peers []int, storage RaftStorage) (*Node, error) {
+	state, err := storage.Load()
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{
+		ID:             id,
+		State:          Follower,
+		PreVote:        true,
+		CheckQuorum:    true,
+		LeaderID:       -1,
+		Peers:          peers,
+		CurrentTerm:    state.Term,
+		VotedFor:       state.VotedFor,
+		Log:            state.Log,
+		CommitIndex:    state.SnapshotIndex,
+		LastApplied:    state.SnapshotIndex,
+		NextIndex:      make(map[int]int),
+		MatchIndex:     make(map[int]int),
+		sessions:       make(map[int64]session),
+		waiters:        make(map[int][]waiter),
+		storage:        storage,
+		snapshotIndex:  state.SnapshotIndex,
+		snapshotTerm:   state.SnapshotTerm,
+		snapshot:       state.Snapshot,
+		snapshotOffset: make(map[int]int),
+		rand:           rand.New(rand.NewSource(time.Now().UnixNano() + int64(id))),
+	}
+	if state.SnapshotIndex > 0 {
+		if _, err := decodeSnapshot(state.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+	node.applyCond = sync.NewCond(&node.mutex)
+	return node, nil
+}
+
+// Обрабатывает сообщения узла, пока transport не будет закрыт
+func (n *Node) Run(wg *sync.WaitGroup, transport Transport[MessageRaft]) {
 	defer wg.Done()
-	go n.runElectionTimer(nodes)
+	n.start(transport, realClock{})
+	go n.runApplier()
+	for msg := range transport.Receive() {
+		n.step(msg)
+	}
+	n.stop()
+}
 
-	for msg := range n.Inbox {
-		switch msg.Type {
-		case "RequestVote":
-			n.handleRequestVote(msg, nodes)
-		case "RequestVoteReply":
-			n.handleRequestVoteReply(msg, nodes)
-		case "AppendEntries":
-			n.handleAppendEntries(msg, nodes)
-		case "AppendEntriesReply":
-			n.handleAppendEntriesReply(msg, nodes)
+// Подключает узел к сети и запускает таймер выборов. Сообщения узлу
+// передаются вызовами step: из Run или из симулятора.
+func (n *Node) start(transport Transport[MessageRaft], clock Clock) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.transport = transport
+	n.clock = clock
+	if n.snapshotIndex > 0 {
+		// Снимок проверен в NewNode, ошибку может вернуть только StateMachine
+		if err := n.restoreSnapshot(); err != nil {
+			panic(fmt.Sprintf("raft: node %d cannot restore snapshot: %v", n.ID, err))
 		}
 	}
+	n.resetElectionTimer()
 }
 
-func (n *Node) runElectionTimer(nodes map[int]*Node) {
-	for {
-		timeout := time.Duration(rand.Intn(150)+150) * time.Millisecond
-		electionTimer := time.NewTimer(timeout)
+// Останавливает таймеры и applier и закрывает хранилище;
+// после stop узел не реагирует на сообщения
+func (n *Node) stop() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.stopped = true
+	n.State = Follower
+	if n.electionTimer != nil {
+		n.electionTimer.Stop()
+	}
+	if err := n.storage.Close(); err != nil {
+		n.logf("Node %d: Closing storage: %v\n", n.ID, err)
+	}
+	n.applyCond.Broadcast()
+}
+
+// Обрабатывает одно входящее сообщение
+func (n *Node) step(msg MessageRaft) {
+	n.mutex.Lock()
+	stopped := n.stopped
+	n.mutex.Unlock()
+	if stopped {
+		return
+	}
+
+	switch msg.Type {
+	case "PreVote":
+		n.handlePreVote(msg)
+	case "PreVoteReply":
+		n.handlePreVoteReply(msg)
+	case "RequestVote":
+		n.handleRequestVote(msg)
+	case "RequestVoteReply":
+		n.handleRequestVoteReply(msg)
+	case "AppendEntries":
+		n.handleAppendEntries(msg)
+	case "AppendEntriesReply":
+		n.handleAppendEntriesReply(msg)
+	case "InstallSnapshot":
+		n.handleInstallSnapshot(msg)
+	case "InstallSnapshotReply":
+		n.handleInstallSnapshotReply(msg)
+	}
+}
+
+// Заводит таймер выборов заново со случайным таймаутом. Вызывается под mutex.
+func (n *Node) resetElectionTimer() {
+	if n.electionTimer != nil {
+		n.electionTimer.Stop()
+	}
+	n.electionGen++
+	gen := n.electionGen
+	timeout := electionTimeoutMin + time.Duration(n.rand.Int63n(int64(electionTimeoutMin)))
+	n.electionTimer = n.clock.AfterFunc(timeout, func() { n.electionTimeout(gen) })
+}
+
+func (n *Node) electionTimeout(gen int) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.stopped || gen != n.electionGen {
+		return
+	}
+
+	if n.State != Leader {
+		if n.PreVote {
+			n.startPreVote()
+		} else {
+			n.startElection()
+		}
+	}
+	n.resetElectionTimer()
+}
+
+// Спрашивает узлы, проголосовали бы они за нас в следующем сроке. Свой срок
+// при этом не растёт; выборы начинаются, только если согласно большинство.
+// Вызывается под mutex.
+func (n *Node) startPreVote() {
+	n.logf("Node %d: Election timeout, starting pre-vote for term %d\n", n.ID, n.CurrentTerm+1)
+	n.State = PreCandidate
+	n.preVotes = map[int]bool{n.ID: true}
+	if len(n.preVotes) > (len(n.Peers)+1)/2 {
+		n.startElection()
+		return
+	}
+
+	for _, peerID := range n.Peers {
+		n.send(peerID, MessageRaft{
+			Type:         "PreVote",
+			Term:         n.CurrentTerm + 1,
+			FromID:       n.ID,
+			ToID:         peerID,
+			LastLogIndex: n.lastLogIndex(),
+			LastLogTerm:  n.getLastLogTerm(),
+		})
+	}
+}
+
+// Переходит в следующий срок и просит голоса. Вызывается под mutex.
+func (n *Node) startElection() {
+	n.logf("Node %d: Becoming Candidate for term %d\n", n.ID, n.CurrentTerm+1)
+	n.State = Candidate
+	n.CurrentTerm++
+	n.VotedFor = n.ID
+	n.votes = map[int]bool{n.ID: true} // Голосуем за себя
+	n.persistState()
+	if len(n.votes) > (len(n.Peers)+1)/2 {
+		n.becomeLeader()
+		return
+	}
+
+	for _, peerID := range n.Peers {
+		n.send(peerID, MessageRaft{
+			Type:         "RequestVote",
+			Term:         n.CurrentTerm,
+			FromID:       n.ID,
+			ToID:         peerID,
+			LastLogIndex: n.lastLogIndex(),
+			LastLogTerm:  n.getLastLogTerm(),
+		})
+	}
+}
 
-		<-electionTimer.C
+// Отвечает, проголосовал бы узел за кандидата в сроке msg.Term. Ни срок,
+// ни VotedFor при этом не меняются. Пока жив известный лидер, узел
+// отказывает: так вернувшийся в сеть узел не может начать выборы.
+func (n *Node) handlePreVote(msg MessageRaft) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	leaderAlive := n.State == Leader || n.clock.Now().Sub(n.leaderContact) < electionTimeoutMin
+	granted := msg.Term > n.CurrentTerm && !leaderAlive && n.logUpToDate(msg.LastLogIndex, msg.LastLogTerm)
+	reply := MessageRaft{
+		Type:        "PreVoteReply",
+		FromID:      n.ID,
+		ToID:        msg.FromID,
+		Term:        n.CurrentTerm,
+		VoteGranted: granted,
+	}
+	if granted {
+		// Согласие относится к сроку кандидата, а не к нашему
+		reply.Term = msg.Term
+	}
+	n.send(msg.FromID, reply)
+}
+
+func (n *Node) handlePreVoteReply(msg MessageRaft) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if !msg.VoteGranted {
+		// Кластер уже в более позднем сроке — догоняем его
+		if msg.Term > n.CurrentTerm {
+			n.stepDown(msg.Term)
+		}
+		return
+	}
+	if n.State != PreCandidate || msg.Term != n.CurrentTerm+1 {
+		return
+	}
+
+	n.preVotes[msg.FromID] = true
+	if len(n.preVotes) > (len(n.Peers)+1)/2 {
+		n.startElection()
+	}
+}
+
+// Переходит в более поздний срок, о котором узнал из сообщения, и становится
+// follower'ом; лидер нового срока пока неизвестен. Вызывается под mutex.
+func (n *Node) stepDown(term int) {
+	n.CurrentTerm = term
+	n.State = Follower
+	n.VotedFor = -1
+	n.LeaderID = -1
+	n.persistState()
+}
+
+// Лог кандидата не отстаёт от нашего: его последняя запись из более
+// позднего срока или из того же срока, но не короче (Raft, раздел 5.4.1)
+func (n *Node) logUpToDate(lastIndex, lastTerm int) bool {
+	if lastTerm != n.getLastLogTerm() {
+		return lastTerm > n.getLastLogTerm()
+	}
+	return lastIndex >= n.lastLogIndex()
+}
+
+// Голосует по правилам Raft (разделы 5.2 и 5.4.1): не больше одного голоса
+// за срок и только за кандидата, лог которого не отстаёт от нашего.
+// Отвечает и при отказе: по сроку в ответе устаревший кандидат узнаёт,
+// что кластер ушёл вперёд.
+func (n *Node) handleRequestVote(msg MessageRaft) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if msg.Term > n.CurrentTerm {
+		n.stepDown(msg.Term)
+	}
+
+	granted := msg.Term == n.CurrentTerm &&
+		(n.VotedFor == -1 || n.VotedFor == msg.FromID) &&
+		n.logUpToDate(msg.LastLogIndex, msg.LastLogTerm)
+	if granted {
+		n.VotedFor = msg.FromID
+		// Голос должен быть на диске раньше, чем кандидат его получит
+		n.persistState()
+		// Отдав голос, не начинаем своих выборов, пока у кандидата есть время победить
+		n.resetElectionTimer()
+		n.logf("Node %d: Voting for Node %d in term %d\n", n.ID, msg.FromID, n.CurrentTerm)
+	} else {
+		n.logf("Node %d: Rejecting vote for Node %d in term %d\n", n.ID, msg.FromID, msg.Term)
+	}
+	n.send(msg.FromID, MessageRaft{
+		Type:        "RequestVoteReply",
+		FromID:      n.ID,
+		ToID:        msg.FromID,
+		Term:        n.CurrentTerm,
+		VoteGranted: granted,
+	})
+}
+
+func (n *Node) handleRequestVoteReply(msg MessageRaft) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if msg.Term > n.CurrentTerm {
+		n.stepDown(msg.Term)
+		return
+	}
+	// Ответ из прошлого срока, отказ или выборы уже закончились
+	if n.State != Candidate || msg.Term != n.CurrentTerm || !msg.VoteGranted {
+		return
+	}
+
+	// Повторный ответ того же узла (например, дубль в сети) не даёт второго голоса
+	n.votes[msg.FromID] = true
+	n.logf("Node %d: Received vote from %d, total votes: %d\n", n.ID, msg.FromID, len(n.votes))
+	if len(n.votes) > (len(n.Peers)+1)/2 {
+		n.logf("Node %d: Became Leader\n", n.ID)
+		n.becomeLeader()
+	}
+}
+
+func (n *Node) becomeLeader() {
+	n.State = Leader
+	n.LeaderID = n.ID
+
+	// Пустая запись текущего срока: лидер может коммитить только записи
+	// своего срока, а вместе с ней закоммитятся и все предыдущие
+	n.appendLog(n.lastLogIndex()+1, LogEntry{Term: n.CurrentTerm})
 
+	for _, peerID := range n.Peers {
+		n.NextIndex[peerID] = n.lastLogIndex()
+		n.MatchIndex[peerID] = 0
+	}
+	n.snapshotOffset = make(map[int]int)
+	n.active = make(map[int]bool)
+
+	n.sendHeartbeats()
+	n.scheduleHeartbeat(n.CurrentTerm)
+	if n.CheckQuorum {
+		n.scheduleQuorumCheck(n.CurrentTerm)
+	}
+}
+
+// Пока узел остаётся лидером в данном сроке, периодически рассылает AppendEntries
+func (n *Node) scheduleHeartbeat(term int) {
+	n.clock.AfterFunc(heartbeatInterval, func() {
 		n.mutex.Lock()
-		if n.State == Follower {
-			fmt.Printf("Node %d: Election timeout, becoming Candidate\n", n.ID)
-			n.State = Candidate
-			n.CurrentTerm++
-			n.VotedFor = n.ID
-			n.countVotes = 1 // Голосуем за себя
-
-			for _, peerID := range n.Peers {
-				nodes[peerID].Inbox <- MessageRaft{
-					Type:         "RequestVote",
-					Term:         n.CurrentTerm,
-					FromID:       n.ID,
-					LastLogIndex: len(n.Log) - 1,
-					LastLogTerm:  n.getLastLogTerm(),
-				}
-			}
+		defer n.mutex.Unlock()
+		if n.stopped || n.State != Leader || n.CurrentTerm != term {
+			return
 		}
-		n.mutex.Unlock()
+		n.sendHeartbeats()
+		n.scheduleHeartbeat(term)
+	})
+}
+
+// Раз в checkQuorumInterval проверяет, что лидеру ответило большинство.
+// Иначе лидер, скорее всего, отрезан от кластера и уходит в follower'ы,
+// а большинство тем временем выберет нового.
+func (n *Node) scheduleQuorumCheck(term int) {
+	n.clock.AfterFunc(checkQuorumInterval, func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		if n.stopped || n.State != Leader || n.CurrentTerm != term {
+			return
+		}
+		if len(n.active)+1 <= (len(n.Peers)+1)/2 {
+			n.logf("Node %d: Lost contact with the majority, stepping down\n", n.ID)
+			n.State = Follower
+			n.LeaderID = -1
+			n.resetElectionTimer()
+			return
+		}
+		n.active = make(map[int]bool)
+		n.scheduleQuorumCheck(term)
+	})
+}
+
+func (n *Node) sendHeartbeats() {
+	for _, peerID := range n.Peers {
+		n.sendAppendEntries(peerID)
 	}
 }
 
-func (n *Node) handleRequestVote(msg MessageRaft, nodes map[int]*Node) {
+// Отправляет узлу peerID записи начиная с NextIndex (или пустой heartbeat).
+// Если нужные записи уже вошли в снимок, отправляет очередную часть снимка.
+func (n *Node) sendAppendEntries(peerID int) {
+	prevIndex := n.NextIndex[peerID] - 1
+	if prevIndex < n.snapshotIndex {
+		n.sendSnapshotChunk(peerID)
+		return
+	}
+	last := min(n.lastLogIndex(), prevIndex+maxEntriesPerMessage)
+	entries := make([]LogEntry, last-prevIndex)
+	copy(entries, n.Log[prevIndex-n.snapshotIndex:last-n.snapshotIndex])
+
+	n.send(peerID, MessageRaft{
+		Type:         "AppendEntries",
+		Term:         n.CurrentTerm,
+		FromID:       n.ID,
+		ToID:         peerID,
+		PrevLogIndex: prevIndex,
+		PrevLogTerm:  n.logTerm(prevIndex),
+		Entries:      entries,
+		LeaderCommit: n.CommitIndex,
+	})
+}
+
+func (n *Node) handleAppendEntries(msg MessageRaft) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
+	reply := MessageRaft{
+		Type:   "AppendEntriesReply",
+		FromID: n.ID,
+		ToID:   msg.FromID,
+	}
+
 	if msg.Term < n.CurrentTerm {
+		reply.Term = n.CurrentTerm
+		n.send(msg.FromID, reply)
 		return
 	}
 
 	if msg.Term > n.CurrentTerm {
 		n.CurrentTerm = msg.Term
-		n.State = Follower
 		n.VotedFor = -1
+		n.persistState()
 	}
+	n.State = Follower
+	n.LeaderID = msg.FromID
+	n.leaderContact = n.clock.Now()
+	n.resetElectionTimer()
+	reply.Term = n.CurrentTerm
 
-	if n.VotedFor == -1 || n.VotedFor == msg.FromID {
-		n.VotedFor = msg.FromID
-		fmt.Printf("Node %d: Voting for Node %d\n", n.ID, msg.FromID)
-		nodes[msg.FromID].Inbox <- MessageRaft{
-			Type:        "RequestVoteReply",
-			FromID:      n.ID,
-			ToID:        msg.FromID,
-			Term:        n.CurrentTerm,
-			VoteGranted: true,
+	// Записи до snapshotIndex закоммичены и уже есть в снимке
+	if msg.PrevLogIndex < n.snapshotIndex {
+		skip := min(n.snapshotIndex-msg.PrevLogIndex, len(msg.Entries))
+		msg.Entries = msg.Entries[skip:]
+		msg.PrevLogIndex, msg.PrevLogTerm = n.snapshotIndex, n.snapshotTerm
+	}
+
+	// Проверка согласованности: у нас должна быть запись PrevLogIndex из срока PrevLogTerm
+	if msg.PrevLogIndex > n.lastLogIndex() {
+		reply.ConflictIndex = n.lastLogIndex() + 1
+		n.send(msg.FromID, reply)
+		return
+	}
+	if term := n.logTerm(msg.PrevLogIndex); term != msg.PrevLogTerm {
+		// Подсказываем лидеру начало конфликтующего срока, чтобы пропустить его целиком
+		reply.ConflictTerm = term
+		reply.ConflictIndex = msg.PrevLogIndex
+		for reply.ConflictIndex > n.snapshotIndex+1 && n.logTerm(reply.ConflictIndex-1) == term {
+			reply.ConflictIndex--
+		}
+		n.send(msg.FromID, reply)
+		return
+	}
+
+	// Отрезаем конфликтующий хвост и дописываем новые записи. Совпадающие записи
+	// не трогаем: запоздавший AppendEntries не должен укорачивать лог.
+	for i, entry := range msg.Entries {
+		index := msg.PrevLogIndex + 1 + i
+		if index <= n.lastLogIndex() {
+			if n.logTerm(index) == entry.Term {
+				continue
+			}
 		}
+		n.appendLog(index, msg.Entries[i:]...)
+		break
+	}
+
+	// lastNew может быть меньше CommitIndex, если сообщение запоздало:
+	// индекс коммита только растёт
+	lastNew := msg.PrevLogIndex + len(msg.Entries)
+	if c := min(msg.LeaderCommit, lastNew); c > n.CommitIndex {
+		n.CommitIndex = c
+		n.applyCommitted()
 	}
+
+	reply.Success = true
+	reply.MatchIndex = lastNew
+	n.send(msg.FromID, reply)
 }
 
-func (n *Node) handleRequestVoteReply(msg MessageRaft, nodes map[int]*Node) {
+func (n *Node) handleAppendEntriesReply(msg MessageRaft) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
-	if n.State != Candidate || msg.Term < n.CurrentTerm {
+	if msg.Term > n.CurrentTerm {
+		n.stepDown(msg.Term)
 		return
 	}
 
-	if msg.VoteGranted {
-		n.countVotes++
-		fmt.Printf("Node %d: Received vote from %d, total votes: %d\n", n.ID, msg.FromID, n.countVotes)
+	// Ответ на сообщение из прошлого срока или узел уже не лидер
+	if n.State != Leader || msg.Term != n.CurrentTerm {
+		return
 	}
+	n.active[msg.FromID] = true
 
-	if n.countVotes > len(nodes)/2 {
-		fmt.Printf("Node %d: Became Leader\n", n.ID)
-		n.State = Leader
-		n.LeaderID = n.ID
+	if msg.Success {
+		if msg.MatchIndex > n.MatchIndex[msg.FromID] {
+			n.MatchIndex[msg.FromID] = msg.MatchIndex
+		}
+		n.NextIndex[msg.FromID] = min(n.MatchIndex[msg.FromID], n.lastLogIndex()) + 1
+		n.advanceCommitIndex()
 
-		for _, peerID := range n.Peers {
-			n.NextIndex[peerID] = len(n.Log)
-			n.MatchIndex[peerID] = 0
+		// Если follower ещё отстаёт, сразу шлём следующую порцию
+		if n.NextIndex[msg.FromID] <= n.lastLogIndex() {
+			n.sendAppendEntries(msg.FromID)
 		}
+		return
+	}
 
-		n.sendHeartbeats(nodes)
+	// Откатываем NextIndex по подсказке follower'а
+	next := msg.ConflictIndex
+	if msg.ConflictTerm > 0 {
+		for i := n.lastLogIndex(); i > n.snapshotIndex; i-- {
+			if n.logTerm(i) == msg.ConflictTerm {
+				next = i + 1
+				break
+			}
+		}
 	}
+	next = max(1, min(next, n.lastLogIndex()+1))
+	// Запоздавший отказ не должен откатывать NextIndex ниже подтверждённого
+	n.NextIndex[msg.FromID] = max(next, n.MatchIndex[msg.FromID]+1)
+	n.sendAppendEntries(msg.FromID)
 }
 
-func (n *Node) sendHeartbeats(nodes map[int]*Node) {
-	for _, peerID := range n.Peers {
-		nodes[peerID].Inbox <- MessageRaft{
-			Type:   "AppendEntries",
-			Term:   n.CurrentTerm,
-			FromID: n.ID,
-		}
+// Отправляет follower'у часть снимка, начиная с уже принятого им смещения
+func (n *Node) sendSnapshotChunk(peerID int) {
+	offset := n.snapshotOffset[peerID]
+	if offset > len(n.snapshot) {
+		offset = 0
 	}
+	end := min(offset+snapshotChunkSize, len(n.snapshot))
+	n.send(peerID, MessageRaft{
+		Type:          "InstallSnapshot",
+		Term:          n.CurrentTerm,
+		FromID:        n.ID,
+		ToID:          peerID,
+		SnapshotIndex: n.snapshotIndex,
+		SnapshotTerm:  n.snapshotTerm,
+		Offset:        offset,
+		Data:          n.snapshot[offset:end],
+		Done:          end == len(n.snapshot),
+	})
 }
 
-func (n *Node) handleAppendEntries(msg MessageRaft, nodes map[int]*Node) {
+func (n *Node) handleInstallSnapshot(msg MessageRaft) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
+	reply := MessageRaft{
+		Type:          "InstallSnapshotReply",
+		FromID:        n.ID,
+		ToID:          msg.FromID,
+		SnapshotIndex: msg.SnapshotIndex,
+	}
+
 	if msg.Term < n.CurrentTerm {
-		nodes[msg.FromID].Inbox <- MessageRaft{
-			Type:    "AppendEntriesReply",
-			Term:    n.CurrentTerm,
-			Success: false,
-		}
+		reply.Term = n.CurrentTerm
+		n.send(msg.FromID, reply)
 		return
 	}
 
-	n.CurrentTerm = msg.Term
+	if msg.Term > n.CurrentTerm {
+		n.CurrentTerm = msg.Term
+		n.VotedFor = -1
+		n.persistState()
+	}
 	n.State = Follower
 	n.LeaderID = msg.FromID
+	n.leaderContact = n.clock.Now()
+	n.resetElectionTimer()
+	reply.Term = n.CurrentTerm
+
+	// Всё, что покрывает снимок, у нас уже закоммичено
+	if msg.SnapshotIndex <= n.CommitIndex {
+		reply.Success = true
+		reply.MatchIndex = msg.SnapshotIndex
+		n.send(msg.FromID, reply)
+		return
+	}
+
+	if msg.Offset == 0 {
+		n.incoming = nil
+		n.incomingIndex, n.incomingTerm = msg.SnapshotIndex, msg.SnapshotTerm
+	}
+	// Часть другого снимка — просим лидера начать передачу заново
+	if msg.SnapshotIndex != n.incomingIndex || msg.SnapshotTerm != n.incomingTerm {
+		n.send(msg.FromID, reply)
+		return
+	}
+	// Повторы и части не по порядку пропускаем: в ответе лидер увидит наше смещение
+	if msg.Offset == len(n.incoming) {
+		n.incoming = append(n.incoming, msg.Data...)
+		if msg.Done {
+			n.installSnapshot(msg.SnapshotIndex, msg.SnapshotTerm, n.incoming)
+			n.incoming = nil
+			n.incomingIndex, n.incomingTerm = 0, 0
+		}
+	}
+	reply.Offset = len(n.incoming)
+	if n.snapshotIndex >= msg.SnapshotIndex {
+		reply.Success = true
+		reply.MatchIndex = msg.SnapshotIndex
+	}
+	n.send(msg.FromID, reply)
+}
+
+// Заменяет состояние узла полученным снимком. Записи лога после снимка
+// сохраняются, если лог совпадает с лидером в точке снимка. Вызывается под mutex.
+func (n *Node) installSnapshot(index, term int, data []byte) {
+	if _, err := decodeSnapshot(data); err != nil {
+		n.logf("Node %d: Rejecting snapshot %d: %v\n", n.ID, index, err)
+		return
+	}
+	if err := n.storage.SaveSnapshot(index, term, data); err != nil {
+		panic(fmt.Sprintf("raft: node %d cannot persist snapshot: %v", n.ID, err))
+	}
+	if index < n.lastLogIndex() && n.logTerm(index) == term {
+		n.Log = append([]LogEntry(nil), n.Log[index-n.snapshotIndex:]...)
+	} else {
+		n.Log = nil
+	}
+	n.snapshotIndex, n.snapshotTerm, n.snapshot = index, term, data
+	n.CommitIndex = max(n.CommitIndex, index)
+	n.LastApplied = index
+	if err := n.restoreSnapshot(); err != nil {
+		panic(fmt.Sprintf("raft: node %d cannot restore snapshot: %v", n.ID, err))
+	}
 
-	nodes[msg.FromID].Inbox <- MessageRaft{
-		Type:    "AppendEntriesReply",
-		Term:    n.CurrentTerm,
-		Success: true,
+	// Записи, которых ждали Propose, заменены снимком: исход неизвестен,
+	// клиент повторит запрос, и сессия в снимке не даст применить его дважды.
+	// Уведомляем по порядку индексов, чтобы симуляция воспроизводилась по seed.
+	var replaced []int
+	for i := range n.waiters {
+		if i <= index {
+			replaced = append(replaced, i)
+		}
+	}
+	sort.Ints(replaced)
+	for _, i := range replaced {
+		for _, w := range n.waiters[i] {
+			w.notify(applyResult{err: ErrLeadershipLost})
+		}
+		delete(n.waiters, i)
 	}
 }
 
-func (n *Node) handleAppendEntriesReply(msg MessageRaft, nodes map[int]*Node) {
+func (n *Node) handleInstallSnapshotReply(msg MessageRaft) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
 	if msg.Term > n.CurrentTerm {
-		n.CurrentTerm = msg.Term
-		n.State = Follower
-		n.VotedFor = -1
+		n.stepDown(msg.Term)
+		return
+	}
+	if n.State != Leader || msg.Term != n.CurrentTerm {
 		return
 	}
+	n.active[msg.FromID] = true
 
 	if msg.Success {
-		n.MatchIndex[msg.FromID] = msg.LastLogIndex
-		n.NextIndex[msg.FromID] = msg.LastLogIndex + 1
+		delete(n.snapshotOffset, msg.FromID)
+		if msg.MatchIndex > n.MatchIndex[msg.FromID] {
+			n.MatchIndex[msg.FromID] = msg.MatchIndex
+		}
+		n.NextIndex[msg.FromID] = max(n.NextIndex[msg.FromID], n.MatchIndex[msg.FromID]+1)
+		n.advanceCommitIndex()
+		if n.NextIndex[msg.FromID] <= n.lastLogIndex() {
+			n.sendAppendEntries(msg.FromID)
+		}
+		return
+	}
+
+	// Лидер успел сделать новый снимок — передаём его с начала
+	if msg.SnapshotIndex != n.snapshotIndex {
+		n.snapshotOffset[msg.FromID] = 0
 	} else {
-		if n.NextIndex[msg.FromID] > 0 {
-			n.NextIndex[msg.FromID]--
+		n.snapshotOffset[msg.FromID] = msg.Offset
+	}
+	if n.NextIndex[msg.FromID]-1 < n.snapshotIndex {
+		n.sendSnapshotChunk(msg.FromID)
+	}
+}
+
+// Коммитит наибольший индекс N, реплицированный на большинство,
+// если запись N из текущего срока (Raft, раздел 5.4.2)
+func (n *Node) advanceCommitIndex() {
+	for index := n.lastLogIndex(); index > n.CommitIndex; index-- {
+		if n.logTerm(index) != n.CurrentTerm {
+			break
+		}
+		replicas := 1 // Сам лидер
+		for _, peerID := range n.Peers {
+			if n.MatchIndex[peerID] >= index {
+				replicas++
+			}
+		}
+		if replicas > (len(n.Peers)+1)/2 {
+			n.CommitIndex = index
+			n.applyCommitted()
+			return
 		}
 	}
 }
 
-func (n *Node) getLastLogTerm() int {
-	if len(n.Log) == 0 {
+// Предлагает команду кластеру и ждёт, пока она будет закоммичена и применена.
+// Возвращает индекс и срок записи. Если узел не лидер, возвращает
+// *NotLeaderError с известным лидером, куда стоит повторить запрос.
+func (n *Node) Propose(ctx context.Context, command interface{}) (index, term int, err error) {
+	index, term, _, err = n.propose(ctx, command)
+	return index, term, err
+}
+
+// То же, что Propose, но дополнительно возвращает результат StateMachine.Apply
+func (n *Node) propose(ctx context.Context, command interface{}) (index, term int, result interface{}, err error) {
+	ch := make(chan applyResult, 1)
+	n.mutex.Lock()
+	index, term, err = n.submit(command, func(res applyResult) { ch <- res })
+	n.mutex.Unlock()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return index, term, nil, ctx.Err()
+	case res := <-ch:
+		return res.index, res.term, res.result, res.err
+	}
+}
+
+// Добавляет команду в лог лидера, не дожидаясь коммита, и возвращает индекс
+// и срок её записи. Когда запись будет применена (или заменена записью
+// другого лидера), вызывается notify; для уже применённого запроса — сразу.
+// Если узел упадёт раньше, notify не будет вызван. Вызывается под mutex.
+func (n *Node) submit(command interface{}, notify func(applyResult)) (index, term int, err error) {
+	if n.State != Leader {
+		return 0, 0, &NotLeaderError{LeaderID: n.LeaderID}
+	}
+
+	if req, ok := command.(ClientRequest); ok {
+		// Запрос уже применён — возвращаем его результат
+		if s, exists := n.sessions[req.ClientID]; exists && req.Seq <= s.Seq {
+			if req.Seq < s.Seq {
+				return 0, 0, ErrStaleRequest
+			}
+			notify(applyResult{index: s.Index, term: s.Term, result: s.Result})
+			return s.Index, s.Term, nil
+		}
+		// Запрос уже в логе, но ещё не применён — ждём ту же запись
+		for i := n.LastApplied + 1; i <= n.lastLogIndex(); i++ {
+			if other, ok := n.entry(i).Command.(ClientRequest); ok && other.ClientID == req.ClientID && other.Seq == req.Seq {
+				index = i
+				break
+			}
+		}
+	}
+	if index != 0 {
+		term = n.logTerm(index)
+		n.waiters[index] = append(n.waiters[index], waiter{term: term, notify: notify})
+		return index, term, nil
+	}
+
+	index, term = n.lastLogIndex()+1, n.CurrentTerm
+	n.appendLog(index, LogEntry{Term: term, Command: command})
+	n.waiters[index] = append(n.waiters[index], waiter{term: term, notify: notify})
+	if n.transport != nil {
+		n.sendHeartbeats()
+	}
+	// Кластер из одного узла коммитит сразу
+	n.advanceCommitIndex()
+	return index, term, nil
+}
+
+// Применяет записи до CommitIndex: обновляет сессии клиентов, применяет
+// команды к StateMachine, будит ожидающих Propose и ставит записи
+// в очередь для ApplyCh. Вызывается под mutex.
+func (n *Node) applyCommitted() {
+	for n.LastApplied < n.CommitIndex {
+		n.LastApplied++
+		entry := n.entry(n.LastApplied)
+		res := applyResult{index: n.LastApplied, term: entry.Term}
+		command := entry.Command
+
+		req, isRequest := command.(ClientRequest)
+		duplicate := false
+		if isRequest {
+			command = req.Command
+			if s, exists := n.sessions[req.ClientID]; exists && req.Seq <= s.Seq {
+				// Повтор уже применённого запроса
+				res.index, res.term, res.result = s.Index, s.Term, s.Result
+				command = nil
+				duplicate = true
+			}
+		}
+
+		if command != nil && n.StateMachine != nil {
+			res.result = n.StateMachine.Apply(command)
+		}
+		if isRequest && !duplicate {
+			n.sessions[req.ClientID] = session{Seq: req.Seq, Index: n.LastApplied, Term: entry.Term, Result: res.result}
+		}
+
+		if command != nil && n.ApplyCh != nil {
+			n.applyQueue = append(n.applyQueue, ApplyMsg{Index: n.LastApplied, Term: entry.Term, Command: command})
+		}
+		for _, w := range n.waiters[n.LastApplied] {
+			if w.term != entry.Term {
+				w.notify(applyResult{err: ErrLeadershipLost})
+				continue
+			}
+			w.notify(res)
+		}
+		delete(n.waiters, n.LastApplied)
+	}
+	n.applyCond.Broadcast()
+	n.maybeSnapshot()
+}
+
+// Делает снимок и отбрасывает вошедшие в него записи, если после
+// предыдущего снимка применено SnapshotThreshold записей. Вызывается под mutex.
+func (n *Node) maybeSnapshot() {
+	if n.SnapshotThreshold <= 0 || n.StateMachine == nil || n.LastApplied-n.snapshotIndex < n.SnapshotThreshold {
+		return
+	}
+	state, err := n.StateMachine.Snapshot()
+	if err != nil {
+		n.logf("Node %d: Snapshot failed: %v\n", n.ID, err)
+		return
+	}
+	data, err := n.encodeSnapshot(state)
+	if err != nil {
+		n.logf("Node %d: Snapshot failed: %v\n", n.ID, err)
+		return
+	}
+
+	index, term := n.LastApplied, n.logTerm(n.LastApplied)
+	if err := n.storage.SaveSnapshot(index, term, data); err != nil {
+		panic(fmt.Sprintf("raft: node %d cannot persist snapshot: %v", n.ID, err))
+	}
+	n.Log = append([]LogEntry(nil), n.Log[index-n.snapshotIndex:]...)
+	n.snapshotIndex, n.snapshotTerm, n.snapshot = index, term, data
+	// Передачи старого снимка начнутся заново с новым
+	n.snapshotOffset = make(map[int]int)
+}
+
+func (n *Node) encodeSnapshot(state []byte) ([]byte, error) {
+	sessions := make(map[int64]session, len(n.sessions))
+	for id, s := range n.sessions {
+		if err, ok := s.Result.(error); ok {
+			s.Result = errorResult{Message: err.Error()}
+		}
+		sessions[id] = s
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshotData{Sessions: sessions, State: state}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(data []byte) (snapshotData, error) {
+	var decoded snapshotData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return snapshotData{}, err
+	}
+	if decoded.Sessions == nil {
+		decoded.Sessions = make(map[int64]session)
+	}
+	return decoded, nil
+}
+
+// Восстанавливает сессии и StateMachine из текущего снимка и передаёт
+// снимок в ApplyCh. Вызывается под mutex.
+func (n *Node) restoreSnapshot() error {
+	decoded, err := decodeSnapshot(n.snapshot)
+	if err != nil {
+		return err
+	}
+	n.sessions = decoded.Sessions
+	if n.StateMachine != nil {
+		if err := n.StateMachine.Restore(decoded.State); err != nil {
+			return err
+		}
+	}
+	if n.ApplyCh != nil {
+		n.applyQueue = append(n.applyQueue, ApplyMsg{Index: n.snapshotIndex, Term: n.snapshotTerm, Snapshot: decoded.State})
+		n.applyCond.Broadcast()
+	}
+	return nil
+}
+
+// Передаёт закоммиченные записи в ApplyCh строго по порядку. Отправка идёт
+// без mutex, чтобы медленный получатель не останавливал узел.
+func (n *Node) runApplier() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for {
+		for len(n.applyQueue) == 0 && !n.stopped {
+			n.applyCond.Wait()
+		}
+		if n.stopped {
+			return
+		}
+		batch := n.applyQueue
+		n.applyQueue = nil
+		ch := n.ApplyCh
+
+		n.mutex.Unlock()
+		for _, msg := range batch {
+			ch <- msg
+		}
+		n.mutex.Lock()
+	}
+}
+
+func (n *Node) logf(format string, args ...interface{}) {
+	if n.Logger != nil {
+		n.Logger(format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// Отправляет сообщение, не дожидаясь доставки. Потерянные сообщения
+// восполняются повторами: лидер шлёт AppendEntries каждые heartbeatInterval,
+// кандидат — новые RequestVote по таймауту выборов.
+func (n *Node) send(to int, msg MessageRaft) {
+	n.transport.Send(to, msg)
+}
+
+// Сохраняет срок и голос. Вызывается под mutex до отправки сообщений,
+// которые зависят от нового состояния.
+func (n *Node) persistState() {
+	if err := n.storage.SaveState(n.CurrentTerm, n.VotedFor); err != nil {
+		// Продолжать без долговременного состояния небезопасно
+		panic(fmt.Sprintf("raft: node %d cannot persist state: %v", n.ID, err))
+	}
+}
+
+// Заменяет записи лога начиная с index на entries, сначала в хранилище,
+// затем в памяти. Вызывается под mutex.
+func (n *Node) appendLog(index int, entries ...LogEntry) {
+	if err := n.storage.Append(index, entries); err != nil {
+		panic(fmt.Sprintf("raft: node %d cannot persist log: %v", n.ID, err))
+	}
+	n.Log = append(n.Log[:index-1-n.snapshotIndex], entries...)
+}
+
+// Запись с индексом index; index должен быть после снимка
+func (n *Node) entry(index int) LogEntry {
+	return n.Log[index-1-n.snapshotIndex]
+}
+
+// Индекс последней записи лога с учётом снимка (0, если лог пуст)
+func (n *Node) lastLogIndex() int {
+	return n.snapshotIndex + len(n.Log)
+}
+
+// Срок записи с индексом index (0 для index = 0 и для записей
+// внутри снимка, сроки которых уже неизвестны)
+func (n *Node) logTerm(index int) int {
+	if index == n.snapshotIndex {
+		return n.snapshotTerm
+	}
+	if index < n.snapshotIndex || index > n.lastLogIndex() {
 		return 0
 	}
-	return n.Log[len(n.Log)-1].Term
+	return n.entry(index).Term
+}
+
+func (n *Node) getLastLogTerm() int {
+	return n.logTerm(n.lastLogIndex())
 }
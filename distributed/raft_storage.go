@@ -0,0 +1,508 @@
+package distributed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"wintersc/storage"
+)
+
+// Долговременное состояние узла Raft: срок, голос и лог. Узел обязан
+// сохранить их до того, как ответить на RPC, иначе после перезапуска
+// он может проголосовать дважды в одном сроке.
+type RaftStorage interface {
+	SaveState(term, votedFor int) error
+	// Отрезает лог начиная с index и дописывает entries (первая получит индекс index).
+	// index должен быть в пределах от первой записи после снимка до записи
+	// за последней: пропуски и записи внутри снимка — ошибка.
+	Append(index int, entries []LogEntry) error
+	// Сохраняет снимок автомата, покрывающий записи до index включительно,
+	// и отбрасывает эти записи. Если запись index в логе из другого срока
+	// или её нет, лог отбрасывается целиком. Снимок не новее текущего
+	// игнорируется.
+	SaveSnapshot(index, term int, data []byte) error
+	// Всё сохранённое состояние; для нового хранилища — VotedFor = -1 и пустой лог
+	Load() (PersistentState, error)
+	// Освобождает открытые файлы. Узел закрывает хранилище, когда
+	// останавливается; перезапущенный узел снова вызывает Load.
+	Close() error
+}
+
+// Состояние, которое узел восстанавливает при перезапуске
+type PersistentState struct {
+	Term          int
+	VotedFor      int
+	SnapshotIndex int // Индекс последней записи, вошедшей в снимок (0 — снимка нет)
+	SnapshotTerm  int
+	Snapshot      []byte
+	Log           []LogEntry // Записи с индексами SnapshotIndex+1, SnapshotIndex+2, ...
+}
+
+// Команды в логе хранятся как interface{}, поэтому gob должен знать
+// их конкретные типы. Свои типы команд нужно зарегистрировать так же.
+func init() {
+	gob.Register(ClientRequest{})
+	gob.Register(KVCommand{})
+	gob.Register(storage.Record{})
+	gob.Register(KVResult{})
+	gob.Register(errorResult{})
+}
+
+// Хранилище в памяти: переживает «перезапуск» узла внутри процесса, для тестов
+type MemoryStorage struct {
+	mutex sync.Mutex
+	state PersistentState
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{state: PersistentState{VotedFor: -1}}
+}
+
+func (s *MemoryStorage) SaveState(term, votedFor int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state.Term, s.state.VotedFor = term, votedFor
+	return nil
+}
+
+func (s *MemoryStorage) Append(index int, entries []LogEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	first := s.state.SnapshotIndex + 1
+	if index < first || index > first+len(s.state.Log) {
+		return fmt.Errorf("raft storage: append at %d, log has entries %d..%d", index, first, first+len(s.state.Log)-1)
+	}
+	s.state.Log = append(s.state.Log[:index-first], entries...)
+	return nil
+}
+
+func (s *MemoryStorage) SaveSnapshot(index, term int, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if index <= s.state.SnapshotIndex {
+		return nil
+	}
+	if pos := index - s.state.SnapshotIndex; pos <= len(s.state.Log) && s.state.Log[pos-1].Term == term {
+		s.state.Log = append([]LogEntry(nil), s.state.Log[pos:]...)
+	} else {
+		s.state.Log = nil
+	}
+	s.state.SnapshotIndex, s.state.SnapshotTerm = index, term
+	s.state.Snapshot = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+func (s *MemoryStorage) Load() (PersistentState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state := s.state
+	state.Log = append([]LogEntry(nil), s.state.Log...)
+	return state, nil
+}
+
+// Хранилище в каталоге на диске:
+//
+//	state               — срок и голос, перезаписывается атомарно через rename
+//	snapshot            — индекс и срок снимка, данные автомата, crc32;
+//	                      тоже перезаписывается через rename
+//	log-<first>.seg     — сегменты лога по SegmentEntries записей,
+//	                      first — индекс первой записи сегмента
+//
+// Каждая запись сегмента: [длина uint32][crc32 uint32][gob(LogEntry)].
+// Оборванная последняя запись после сбоя отбрасывается при загрузке.
+// Сегменты, целиком вошедшие в снимок, удаляются после записи снимка;
+// если сбой случился раньше, они будут удалены при следующей загрузке.
+// Append и SaveSnapshot сверяются с состоянием, прочитанным Load, поэтому
+// до первого Load они возвращают ошибку.
+type FileStorage struct {
+	SegmentEntries int // Записей в сегменте (0 — 1024)
+
+	mutex         sync.Mutex
+	dir           string
+	loaded        bool
+	snapshotIndex int
+	segments      []*segment
+	current       *os.File // Открытый на запись последний сегмент
+}
+
+type segment struct {
+	first   int
+	path    string
+	offsets []int64 // Смещение каждой записи в файле
+	terms   []int   // Срок каждой записи
+	size    int64
+}
+
+var (
+	errCorruptState    = errors.New("raft storage: corrupt state file")
+	errCorruptSnapshot = errors.New("raft storage: corrupt snapshot file")
+	errNotLoaded       = errors.New("raft storage: Load must be called first")
+)
+
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+func (s *FileStorage) SaveState(term, votedFor int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(term))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(int64(votedFor)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	return writeFileSync(s.dir, "state", buf)
+}
+
+func (s *FileStorage) SaveSnapshot(index, term int, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.loaded {
+		return errNotLoaded
+	}
+	if index <= s.snapshotIndex {
+		return nil
+	}
+
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(index))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(term))
+	buf = append(buf, data...)
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	if err := writeFileSync(s.dir, "snapshot", buf); err != nil {
+		return err
+	}
+	s.snapshotIndex = index
+	return s.compact(index, term)
+}
+
+func (s *FileStorage) Load() (PersistentState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := PersistentState{VotedFor: -1}
+	data, err := os.ReadFile(filepath.Join(s.dir, "state"))
+	switch {
+	case err == nil:
+		if len(data) != 20 || crc32.ChecksumIEEE(data[:16]) != binary.LittleEndian.Uint32(data[16:]) {
+			return PersistentState{}, errCorruptState
+		}
+		state.Term = int(binary.LittleEndian.Uint64(data))
+		state.VotedFor = int(int64(binary.LittleEndian.Uint64(data[8:])))
+	case !os.IsNotExist(err):
+		return PersistentState{}, err
+	}
+
+	data, err = os.ReadFile(filepath.Join(s.dir, "snapshot"))
+	switch {
+	case err == nil:
+		body := len(data) - 4
+		if body < 16 || crc32.ChecksumIEEE(data[:body]) != binary.LittleEndian.Uint32(data[body:]) {
+			return PersistentState{}, errCorruptSnapshot
+		}
+		state.SnapshotIndex = int(binary.LittleEndian.Uint64(data))
+		state.SnapshotTerm = int(binary.LittleEndian.Uint64(data[8:]))
+		state.Snapshot = data[16:body]
+	case !os.IsNotExist(err):
+		return PersistentState{}, err
+	}
+
+	first, log, err := s.loadSegments()
+	if err != nil {
+		return PersistentState{}, err
+	}
+	if len(log) > 0 && first > state.SnapshotIndex+1 {
+		return PersistentState{}, fmt.Errorf("raft storage: log starts at %d, snapshot ends at %d", first, state.SnapshotIndex)
+	}
+	// Доделываем уплотнение, прерванное сбоем после записи снимка
+	if state.SnapshotIndex > 0 {
+		if err := s.compact(state.SnapshotIndex, state.SnapshotTerm); err != nil {
+			return PersistentState{}, err
+		}
+		if s.lastSegment() == nil {
+			log = nil
+		} else {
+			log = log[state.SnapshotIndex+1-first:]
+		}
+	}
+	state.Log = log
+	s.loaded, s.snapshotIndex = true, state.SnapshotIndex
+	return state, nil
+}
+
+// Удаляет сегменты, целиком вошедшие в снимок (index, term), или все сегменты,
+// если запись index в логе отсутствует или из другого срока
+func (s *FileStorage) compact(index, term int) error {
+	keep := false
+	for _, seg := range s.segments {
+		if pos := index - seg.first; pos >= 0 && pos < len(seg.terms) {
+			keep = seg.terms[pos] == term
+		}
+	}
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		if keep && seg.first+len(seg.terms) > index+1 {
+			break
+		}
+		if seg == s.lastSegment() {
+			s.closeCurrent()
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
+
+// Читает все сегменты по порядку и восстанавливает индекс смещений.
+// Возвращает индекс первой записи и записи подряд.
+func (s *FileStorage) loadSegments() (int, []LogEntry, error) {
+	s.closeCurrent()
+	s.segments = nil
+
+	matches, err := filepath.Glob(filepath.Join(s.dir, "log-*.seg"))
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, path := range matches {
+		var first int
+		if _, err := fmt.Sscanf(filepath.Base(path), "log-%d.seg", &first); err == nil {
+			s.segments = append(s.segments, &segment{first: first, path: path})
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].first < s.segments[j].first })
+
+	if len(s.segments) == 0 {
+		return 0, nil, nil
+	}
+	first := s.segments[0].first
+	var log []LogEntry
+	for i, seg := range s.segments {
+		if seg.first != first+len(log) {
+			return 0, nil, fmt.Errorf("raft storage: segment %s does not continue the log at %d", seg.path, first+len(log))
+		}
+		data, err := os.ReadFile(seg.path)
+		if err != nil {
+			return 0, nil, err
+		}
+		entries, offsets, valid := decodeSegment(data)
+		if valid != int64(len(data)) {
+			// Повреждение допустимо только в хвосте последнего сегмента
+			if i != len(s.segments)-1 {
+				return 0, nil, fmt.Errorf("raft storage: corrupt segment %s", seg.path)
+			}
+			if err := os.Truncate(seg.path, valid); err != nil {
+				return 0, nil, err
+			}
+		}
+		seg.offsets, seg.size = offsets, valid
+		seg.terms = make([]int, len(entries))
+		for j, entry := range entries {
+			seg.terms[j] = entry.Term
+		}
+		log = append(log, entries...)
+	}
+	return first, log, nil
+}
+
+func decodeSegment(data []byte) (entries []LogEntry, offsets []int64, valid int64) {
+	offset := 0
+	for len(data)-offset >= 8 {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		sum := binary.LittleEndian.Uint32(data[offset+4:])
+		end := offset + 8 + size
+		if end > len(data) || crc32.ChecksumIEEE(data[offset+8:end]) != sum {
+			break
+		}
+		var entry LogEntry
+		if err := gob.NewDecoder(bytes.NewReader(data[offset+8 : end])).Decode(&entry); err != nil {
+			break
+		}
+		entries = append(entries, entry)
+		offsets = append(offsets, int64(offset))
+		offset = end
+	}
+	return entries, offsets, int64(offset)
+}
+
+func (s *FileStorage) Append(index int, entries []LogEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.loaded {
+		return errNotLoaded
+	}
+	if last := s.lastIndex(); index <= s.snapshotIndex || index > last+1 {
+		return fmt.Errorf("raft storage: append at %d, log has entries %d..%d", index, s.snapshotIndex+1, last)
+	}
+
+	if err := s.truncate(index); err != nil {
+		return err
+	}
+	perSegment := s.SegmentEntries
+	if perSegment <= 0 {
+		perSegment = 1024
+	}
+
+	for i, entry := range entries {
+		last := s.lastSegment()
+		if last == nil || len(last.offsets) >= perSegment {
+			if err := s.startSegment(index + i); err != nil {
+				return err
+			}
+			last = s.lastSegment()
+		}
+		if s.current == nil {
+			file, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return err
+			}
+			s.current = file
+		}
+
+		var payload bytes.Buffer
+		if err := gob.NewEncoder(&payload).Encode(entry); err != nil {
+			return err
+		}
+		record := binary.LittleEndian.AppendUint32(nil, uint32(payload.Len()))
+		record = binary.LittleEndian.AppendUint32(record, crc32.ChecksumIEEE(payload.Bytes()))
+		record = append(record, payload.Bytes()...)
+		if _, err := s.current.Write(record); err != nil {
+			return err
+		}
+		last.offsets = append(last.offsets, last.size)
+		last.terms = append(last.terms, entry.Term)
+		last.size += int64(len(record))
+	}
+
+	if s.current != nil && len(entries) > 0 {
+		return s.current.Sync()
+	}
+	return nil
+}
+
+// Удаляет записи с индексами >= index
+func (s *FileStorage) truncate(index int) error {
+	for len(s.segments) > 0 {
+		last := s.lastSegment()
+		switch {
+		case last.first >= index:
+			// Сегмент целиком за точкой отсечения
+			s.closeCurrent()
+			if err := os.Remove(last.path); err != nil {
+				return err
+			}
+			s.segments = s.segments[:len(s.segments)-1]
+		case last.first+len(last.offsets) > index:
+			s.closeCurrent()
+			keep := index - last.first
+			if err := os.Truncate(last.path, last.offsets[keep]); err != nil {
+				return err
+			}
+			last.size = last.offsets[keep]
+			last.offsets = last.offsets[:keep]
+			last.terms = last.terms[:keep]
+			return nil
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *FileStorage) startSegment(first int) error {
+	if err := s.closeCurrent(); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("log-%020d.seg", first))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	// Новый файл должен пережить сбой вместе с записью каталога
+	if err := syncDir(s.dir); err != nil {
+		file.Close()
+		return err
+	}
+	s.current = file
+	s.segments = append(s.segments, &segment{first: first, path: path})
+	return nil
+}
+
+// Индекс последней записи лога; без записей — индекс снимка
+func (s *FileStorage) lastIndex() int {
+	last := s.lastSegment()
+	if last == nil {
+		return s.snapshotIndex
+	}
+	return max(s.snapshotIndex, last.first+len(last.offsets)-1)
+}
+
+func (s *FileStorage) lastSegment() *segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+func (s *FileStorage) closeCurrent() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+func (s *FileStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closeCurrent()
+}
+
+// Атомарно заменяет файл dir/name: запись во временный файл, fsync, rename
+func writeFileSync(dir, name string, data []byte) error {
+	tmp := filepath.Join(dir, name+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
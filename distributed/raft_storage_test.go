@@ -0,0 +1,83 @@
+package distributed
+
+import (
+	"reflect"
+	"testing"
+)
+
+func entries(terms ...int) []LogEntry {
+	var result []LogEntry
+	for i, term := range terms {
+		result = append(result, LogEntry{Term: term, Command: i})
+	}
+	return result
+}
+
+// Оба хранилища одинаково отклоняют пропуски, записи внутри снимка
+// и устаревшие снимки
+func TestRaftStorageBounds(t *testing.T) {
+	file, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.SegmentEntries = 2
+	for name, s := range map[string]RaftStorage{"memory": NewMemoryStorage(), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+			if _, err := s.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Append(2, entries(1)); err == nil {
+				t.Error("Append с пропуском в пустой лог должен вернуть ошибку")
+			}
+			if err := s.Append(1, entries(1, 1, 2, 2, 3)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Append(7, entries(3)); err == nil {
+				t.Error("Append с пропуском после записи 5 должен вернуть ошибку")
+			}
+			// Перезапись хвоста разрешена
+			if err := s.Append(5, entries(4, 4)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveSnapshot(3, 2, []byte("snap-3")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Append(3, entries(5)); err == nil {
+				t.Error("Append внутрь снимка должен вернуть ошибку")
+			}
+			// Более старый снимок не заменяет текущий
+			if err := s.SaveSnapshot(2, 1, []byte("snap-2")); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.SnapshotIndex != 3 || string(state.Snapshot) != "snap-3" {
+				t.Errorf("снимок %d %q, ожидался 3 \"snap-3\"", state.SnapshotIndex, state.Snapshot)
+			}
+			want := []int{2, 4, 4}
+			var terms []int
+			for _, entry := range state.Log {
+				terms = append(terms, entry.Term)
+			}
+			if !reflect.DeepEqual(terms, want) {
+				t.Errorf("сроки записей после снимка %v, ожидались %v", terms, want)
+			}
+		})
+	}
+}
+
+// Без Load файловое хранилище не знает границ лога и не принимает записи
+func TestFileStorageRequiresLoad(t *testing.T) {
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Append(1, entries(1)); err != errNotLoaded {
+		t.Errorf("Append до Load: %v, ожидалась errNotLoaded", err)
+	}
+}
@@ -0,0 +1,93 @@
+package distributed
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Узел, который то теряет связь с кластером, то возвращается, не должен
+// сбивать стабильного лидера, если включены PreVote и CheckQuorum. Без них
+// вернувшийся узел с выросшим сроком смещает лидера, а отрезанный от
+// кластера лидер так и остаётся лидером.
+func TestFlappingNodeDoesNotDisruptLeader(t *testing.T) {
+	for _, seed := range testSeeds(5) {
+		t.Run(fmt.Sprint("seed=", seed), func(t *testing.T) {
+			on := runFlapping(seed, 5, true)
+			if on.leaderChanges > 0 {
+				t.Errorf("с PreVote и CheckQuorum лидер сменился %d раз, срок %d → %d",
+					on.leaderChanges, on.firstTerm, on.lastTerm)
+			}
+			if on.steppedDown < 0 {
+				t.Error("с CheckQuorum отрезанный лидер остался лидером")
+			}
+
+			off := runFlapping(seed, 5, false)
+			if off.leaderChanges == 0 {
+				t.Error("без PreVote и CheckQuorum вернувшийся узел ни разу не сместил лидера")
+			}
+			if off.steppedDown >= 0 {
+				t.Errorf("без CheckQuorum отрезанный лидер ушёл через %v", off.steppedDown)
+			}
+		})
+	}
+}
+
+type flappingResult struct {
+	leaderChanges       int
+	firstTerm, lastTerm int
+	steppedDown         time.Duration // -1, если отрезанный лидер так и не ушёл
+}
+
+// Один узел flaps раз теряет связь с кластером на секунду, затем
+// от кластера отрезают лидера
+func runFlapping(seed int64, flaps int, enabled bool) flappingResult {
+	sim := NewSimulation(seed, SimOptions{Latency: UniformLatency(time.Millisecond, 10*time.Millisecond)})
+	ids := []int{1, 2, 3, 4, 5}
+	cluster := NewSimRaftCluster(sim, ids, func(n *Node) {
+		n.PreVote = enabled
+		n.CheckQuorum = enabled
+	})
+
+	// Ждём устойчивого лидера
+	sim.RunUntil(func() bool {
+		_, ok := cluster.Leader()
+		return ok
+	}, 10*time.Second)
+	sim.RunFor(time.Second)
+	leader, _ := cluster.Leader()
+	res := flappingResult{firstTerm: cluster.Nodes[leader].CurrentTerm, steppedDown: -1}
+
+	// Считаем смены лидера после каждого события
+	current, term := leader, res.firstTerm
+	run := func(d time.Duration) {
+		sim.RunUntil(func() bool {
+			if id, ok := cluster.Leader(); ok && (id != current || cluster.Nodes[id].CurrentTerm != term) {
+				current, term = id, cluster.Nodes[id].CurrentTerm
+				res.leaderChanges++
+			}
+			return false
+		}, d)
+	}
+
+	flapper := ids[0]
+	if flapper == leader {
+		flapper = ids[1]
+	}
+	for i := 0; i < flaps; i++ {
+		sim.Partition([]int{flapper}, filter(ids, flapper))
+		run(time.Second)
+		sim.Heal()
+		run(time.Second)
+	}
+	res.lastTerm = term
+
+	// Отрезаем лидера от остальных
+	isolated := cluster.Nodes[current]
+	sim.Partition([]int{current}, filter(ids, current))
+	start := sim.Elapsed()
+	if sim.RunUntil(func() bool { return isolated.State != Leader }, 3*time.Second) {
+		res.steppedDown = sim.Elapsed() - start
+	}
+	return res
+}
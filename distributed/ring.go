@@ -1,6 +1,7 @@
 package distributed
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -10,9 +11,15 @@ type RingNode struct {
 	NextID    int
 	LeaderID  int
 	Alive     bool
-	Inbox     chan Message
-	RingNodes map[int]*RingNode
+	RingNodes []int // ID всех узлов кольца в порядке обхода
 	Mutex     sync.Mutex
+	// Активен ли узел кольца. Неактивного получателя отправитель пропускает
+	// и передаёт сообщение следующему за ним. nil — о неактивном узле
+	// отправитель узнаёт только по ошибке транспорта.
+	PeerAlive func(id int) bool
+	// Куда писать журнал событий узла (nil — стандартный вывод)
+	Logger    func(format string, args ...interface{})
+	transport Transport[Message]
 }
 
 type Message struct {
@@ -24,43 +31,68 @@ type Message struct {
 
 func NewRingNode(id int) *RingNode {
 	return &RingNode{
-		ID:        id,
-		NextID:    -1,
-		LeaderID:  -1,
-		Alive:     true,
-		Inbox:     make(chan Message, 10),
-		RingNodes: make(map[int]*RingNode),
+		ID:       id,
+		NextID:   -1,
+		LeaderID: -1,
+		Alive:    true,
 	}
 }
 
-// Устанавливаем кольцевые связи
+// Устанавливаем кольцевые связи внутри процесса
 func SetupRing(RingNodes []*RingNode) {
+	network := NewMemoryNetwork[Message](10)
+	ids := make([]int, len(RingNodes))
+	byID := make(map[int]*RingNode)
+	for i, RingNode := range RingNodes {
+		ids[i] = RingNode.ID
+		byID[RingNode.ID] = RingNode
+	}
 	for i, RingNode := range RingNodes {
 		nextIndex := (i + 1) % len(RingNodes)
-		RingNode.NextID = RingNodes[nextIndex].ID
-		for _, n := range RingNodes {
-			RingNode.RingNodes[n.ID] = n
-		}
-		go RingNode.Listen()
+		RingNode.PeerAlive = func(id int) bool { return byID[id].Alive }
+		RingNode.Connect(network.Endpoint(RingNode.ID), RingNodes[nextIndex].ID, ids)
 	}
 }
 
+// Подключает узел к кольцу через transport и запускает обработку сообщений.
+// Так узлы кольца могут работать в разных процессах.
+func (n *RingNode) Connect(transport Transport[Message], nextID int, ids []int) {
+	n.attach(transport, nextID, ids)
+	go n.Listen()
+}
+
+func (n *RingNode) attach(transport Transport[Message], nextID int, ids []int) {
+	n.transport = transport
+	n.NextID = nextID
+	n.RingNodes = ids
+}
+
 func (n *RingNode) Listen() {
-	for {
-		select {
-		case msg := <-n.Inbox:
-			switch msg.Kind {
-			case "ELECTION":
-				n.HandleElection(msg)
-			case "COORDINATOR":
-				n.HandleCoordinator(msg)
-			}
-		}
+	for msg := range n.transport.Receive() {
+		n.Handle(msg)
+	}
+}
+
+// Обрабатывает одно входящее сообщение
+func (n *RingNode) Handle(msg Message) {
+	switch msg.Kind {
+	case "ELECTION":
+		n.HandleElection(msg)
+	case "COORDINATOR":
+		n.HandleCoordinator(msg)
 	}
 }
 
+func (n *RingNode) logf(format string, args ...interface{}) {
+	if n.Logger != nil {
+		n.Logger(format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
 func (n *RingNode) StartRingElection() {
-	fmt.Printf("RingNode %d: Начинаю кольцевые выборы\n", n.ID)
+	n.logf("RingNode %d: Начинаю кольцевые выборы\n", n.ID)
 	n.SendMessage(n.NextID, Message{Kind: "ELECTION", IDs: []int{n.ID}, FromID: n.ID})
 }
 
@@ -78,7 +110,7 @@ func (n *RingNode) HandleElection(msg Message) {
 	}
 
 	if msg.FromID == n.ID {
-		fmt.Printf("RingNode %d: Выбран лидер %d, рассылаю COORDINATOR\n", n.ID, maxID)
+		n.logf("RingNode %d: Выбран лидер %d, рассылаю COORDINATOR\n", n.ID, maxID)
 		n.Broadcast(Message{Kind: "COORDINATOR", FromID: maxID})
 	} else {
 		n.SendMessage(n.NextID, msg)
@@ -87,7 +119,7 @@ func (n *RingNode) HandleElection(msg Message) {
 
 // Обработка COORDINATOR
 func (n *RingNode) HandleCoordinator(msg Message) {
-	fmt.Printf("RingNode %d: Принял нового лидера %d\n", n.ID, msg.FromID)
+	n.logf("RingNode %d: Принял нового лидера %d\n", n.ID, msg.FromID)
 	n.Mutex.Lock()
 	n.LeaderID = msg.FromID
 	n.Mutex.Unlock()
@@ -97,20 +129,44 @@ func (n *RingNode) HandleCoordinator(msg Message) {
 	}
 }
 
-// Отправка сообщения узлу
+// Отправка сообщения узлу. Неактивный узел (по PeerAlive или потому,
+// что транспорт его не знает) пропускается: сообщение уходит следующему
+// за ним активному узлу кольца
 func (n *RingNode) SendMessage(to int, msg Message) {
-	if RingNode, ok := n.RingNodes[to]; ok {
-		if RingNode.Alive { // Проверяем, активен ли узел
-			RingNode.Inbox <- msg
-		} else {
-			fmt.Printf("RingNode %d: Узел %d не активен, пропускаю сообщение\n", n.ID, to)
-			n.SendMessage(RingNode.NextID, msg) // Пропускаем неактивный узел
+	for range n.RingNodes {
+		if to != n.ID && n.PeerAlive != nil && !n.PeerAlive(to) {
+			n.logf("RingNode %d: Узел %d не активен, пропускаю сообщение\n", n.ID, to)
+			to = n.successor(to)
+			continue
 		}
+		err := n.transport.Send(to, msg)
+		if errors.Is(err, ErrUnknownPeer) && to != n.ID {
+			n.logf("RingNode %d: Узел %d недоступен, пропускаю сообщение\n", n.ID, to)
+			to = n.successor(to)
+			continue
+		}
+		if err != nil {
+			n.logf("RingNode %d: Не удалось отправить сообщение узлу %d: %v\n", n.ID, to, err)
+		}
+		return
 	}
 }
 
+// Следующий за id узел кольца
+func (n *RingNode) successor(id int) int {
+	for i, other := range n.RingNodes {
+		if other == id {
+			return n.RingNodes[(i+1)%len(n.RingNodes)]
+		}
+	}
+	return n.NextID
+}
+
+// Рассылка всем узлам без пропуска неактивных: каждый получает сообщение один раз
 func (n *RingNode) Broadcast(msg Message) {
-	for _, RingNode := range n.RingNodes {
-		RingNode.Inbox <- msg
+	for _, id := range n.RingNodes {
+		if err := n.transport.Send(id, msg); err != nil {
+			n.logf("RingNode %d: Не удалось отправить сообщение узлу %d: %v\n", n.ID, id, err)
+		}
 	}
 }
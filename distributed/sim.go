@@ -0,0 +1,596 @@
+package distributed
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Детерминированная симуляция сети и времени. Все события — доставка
+// сообщений и срабатывание таймеров — выполняются по очереди в одном потоке
+// в порядке виртуального времени, а все случайные решения (задержки, потери,
+// таймауты выборов) берутся из одного генератора с заданным seed. Поэтому
+// прогон с тем же seed и теми же действиями повторяется в точности,
+// и упавший сценарий можно воспроизвести по его seed.
+type Simulation struct {
+	opts   SimOptions
+	rand   *rand.Rand
+	start  time.Time
+	now    time.Duration
+	events eventQueue
+	seq    uint64
+	group  map[int]int // Партиция: узел → номер группы; пусто — сеть связна
+	nodes  map[int]*simNode
+	last   map[[2]int]time.Duration // Время последней доставки по каналу, для порядка FIFO
+}
+
+// Распределение задержки доставки одного сообщения
+type LatencyFunc func(r *rand.Rand) time.Duration
+
+type SimOptions struct {
+	Latency       LatencyFunc // nil — UniformLatency(1ms, 10ms)
+	DropRate      float64     // Доля потерянных сообщений
+	DuplicateRate float64     // Доля сообщений, доставленных дважды
+	// Доля сообщений, которые могут обогнать отправленные раньше по тому же
+	// каналу. Остальные доставляются по порядку, как в TCP.
+	ReorderRate float64
+	Trace       io.Writer // Журнал событий с виртуальным временем (nil — не писать)
+}
+
+func ConstantLatency(d time.Duration) LatencyFunc {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	return func(r *rand.Rand) time.Duration {
+		return min + time.Duration(r.Int63n(int64(max-min)+1))
+	}
+}
+
+func ExponentialLatency(mean time.Duration) LatencyFunc {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// Нормальное распределение, отрицательные значения заменяются нулём
+func NormalLatency(mean, stddev time.Duration) LatencyFunc {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(math.Max(0, float64(mean)+r.NormFloat64()*float64(stddev)))
+	}
+}
+
+type simNode struct {
+	crashed     bool
+	incarnation int // Растёт при каждом сбое: таймеры прежнего запуска не срабатывают
+	crash       func()
+	restart     func()
+}
+
+func NewSimulation(seed int64, opts SimOptions) *Simulation {
+	if opts.Latency == nil {
+		opts.Latency = UniformLatency(time.Millisecond, 10*time.Millisecond)
+	}
+	return &Simulation{
+		opts:  opts,
+		rand:  rand.New(rand.NewSource(seed)),
+		start: time.Unix(0, 0).UTC(),
+		group: make(map[int]int),
+		nodes: make(map[int]*simNode),
+		last:  make(map[[2]int]time.Duration),
+	}
+}
+
+// Генератор симуляции: нагрузку теста тоже нужно строить на нём,
+// чтобы прогон оставался воспроизводимым
+func (s *Simulation) Rand() *rand.Rand {
+	return s.rand
+}
+
+func (s *Simulation) Now() time.Time {
+	return s.start.Add(s.now)
+}
+
+// Виртуальное время от начала симуляции
+func (s *Simulation) Elapsed() time.Duration {
+	return s.now
+}
+
+// Таймер, не привязанный к узлу (например, для действий теста)
+func (s *Simulation) AfterFunc(d time.Duration, f func()) Timer {
+	return s.schedule(s.now+d, f)
+}
+
+// Выполняет следующее событие. false, если событий больше нет.
+func (s *Simulation) Step() bool {
+	for s.events.Len() > 0 {
+		ev := heap.Pop(&s.events).(*event)
+		if ev.cancelled {
+			continue
+		}
+		s.now = ev.at
+		ev.fired = true
+		ev.fn()
+		return true
+	}
+	return false
+}
+
+// Выполняет события в течение d виртуального времени
+func (s *Simulation) RunFor(d time.Duration) {
+	s.RunUntil(func() bool { return false }, d)
+}
+
+// Выполняет события, пока cond не станет истинным или не пройдёт limit.
+// Условие проверяется после каждого события.
+func (s *Simulation) RunUntil(cond func() bool, limit time.Duration) bool {
+	deadline := s.now + limit
+	for !cond() {
+		if s.events.Len() == 0 || s.events[0].at > deadline {
+			s.now = deadline
+			return false
+		}
+		s.Step()
+	}
+	return true
+}
+
+// Делит узлы на группы: сообщения между группами теряются, в том числе
+// уже отправленные. Узлы, не попавшие ни в одну группу, изолированы.
+func (s *Simulation) Partition(groups ...[]int) {
+	s.group = make(map[int]int)
+	for i, ids := range groups {
+		for _, id := range ids {
+			s.group[id] = i + 1
+		}
+	}
+	s.Logf("network: partition %v\n", groups)
+}
+
+// Восстанавливает связность сети
+func (s *Simulation) Heal() {
+	s.group = make(map[int]int)
+	s.Logf("network: healed\n")
+}
+
+// Останавливает узел: он теряет входящие сообщения, его таймеры
+// не срабатывают, а энергозависимое состояние пропадает
+func (s *Simulation) Crash(id int) {
+	node, ok := s.nodes[id]
+	if !ok || node.crashed {
+		return
+	}
+	node.crashed = true
+	node.incarnation++
+	s.Logf("node %d: crashed\n", id)
+	node.crash()
+}
+
+// Запускает упавший узел заново из его долговременного состояния
+func (s *Simulation) Restart(id int) {
+	node, ok := s.nodes[id]
+	if !ok || !node.crashed {
+		return
+	}
+	node.crashed = false
+	s.Logf("node %d: restarted\n", id)
+	node.restart()
+}
+
+func (s *Simulation) Crashed(id int) bool {
+	node, ok := s.nodes[id]
+	return ok && node.crashed
+}
+
+// Пишет строку журнала с текущим виртуальным временем
+func (s *Simulation) Logf(format string, args ...interface{}) {
+	if s.opts.Trace == nil {
+		return
+	}
+	fmt.Fprintf(s.opts.Trace, "%10.3fms ", float64(s.now)/float64(time.Millisecond))
+	fmt.Fprintf(s.opts.Trace, format, args...)
+}
+
+// Регистрирует узел: crash и restart вызываются из Crash и Restart
+func (s *Simulation) register(id int, crash, restart func()) {
+	s.nodes[id] = &simNode{crash: crash, restart: restart}
+}
+
+// Часы узла: таймеры, заведённые до сбоя узла, не срабатывают после него
+func (s *Simulation) clock(id int) Clock {
+	return nodeClock{sim: s, id: id, incarnation: s.nodes[id].incarnation}
+}
+
+func (s *Simulation) connected(from, to int) bool {
+	if s.Crashed(from) || s.Crashed(to) {
+		return false
+	}
+	return len(s.group) == 0 || (s.group[from] != 0 && s.group[from] == s.group[to])
+}
+
+// Решает судьбу сообщения from → to: моменты доставки его копий
+// (ни одной, если сообщение потеряно)
+func (s *Simulation) route(from, to int) []time.Duration {
+	if !s.connected(from, to) {
+		return nil
+	}
+	if s.rand.Float64() < s.opts.DropRate {
+		s.Logf("network: dropped %d -> %d\n", from, to)
+		return nil
+	}
+	copies := 1
+	if s.rand.Float64() < s.opts.DuplicateRate {
+		copies = 2
+	}
+
+	var times []time.Duration
+	link := [2]int{from, to}
+	for i := 0; i < copies; i++ {
+		at := s.now + s.opts.Latency(s.rand)
+		if s.rand.Float64() >= s.opts.ReorderRate {
+			at = max(at, s.last[link])
+			s.last[link] = at
+		}
+		times = append(times, at)
+	}
+	return times
+}
+
+func (s *Simulation) schedule(at time.Duration, fn func()) *event {
+	s.seq++
+	ev := &event{at: at, seq: s.seq, fn: fn}
+	heap.Push(&s.events, ev)
+	return ev
+}
+
+type nodeClock struct {
+	sim         *Simulation
+	id          int
+	incarnation int
+}
+
+func (c nodeClock) Now() time.Time {
+	return c.sim.Now()
+}
+
+func (c nodeClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.sim.schedule(c.sim.now+d, func() {
+		node := c.sim.nodes[c.id]
+		if !node.crashed && node.incarnation == c.incarnation {
+			f()
+		}
+	})
+}
+
+// Событие симуляции; при равном времени выполняются в порядке планирования
+type event struct {
+	at        time.Duration
+	seq       uint64
+	fn        func()
+	cancelled bool
+	fired     bool
+}
+
+func (e *event) Stop() bool {
+	if e.cancelled || e.fired {
+		return false
+	}
+	e.cancelled = true
+	return true
+}
+
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
+
+// Сеть для сообщений типа M внутри симуляции
+type SimNetwork[M any] struct {
+	sim       *Simulation
+	endpoints map[int]*SimTransport[M]
+}
+
+func NewSimNetwork[M any](sim *Simulation) *SimNetwork[M] {
+	return &SimNetwork[M]{sim: sim, endpoints: make(map[int]*SimTransport[M])}
+}
+
+// Подключает узел id. Сообщения ему доставляются вызовом handler
+// в ходе симуляции, поэтому Receive у SimTransport не используется.
+func (net *SimNetwork[M]) Endpoint(id int, handler func(M)) *SimTransport[M] {
+	t := &SimTransport[M]{network: net, id: id, handler: handler}
+	net.endpoints[id] = t
+	return t
+}
+
+type SimTransport[M any] struct {
+	network *SimNetwork[M]
+	id      int
+	handler func(M)
+	closed  bool
+}
+
+func (t *SimTransport[M]) Send(to int, msg M) error {
+	if t.closed {
+		return ErrClosed
+	}
+	net := t.network
+	if _, ok := net.endpoints[to]; !ok {
+		return ErrUnknownPeer
+	}
+	for _, at := range net.sim.route(t.id, to) {
+		net.sim.schedule(at, func() {
+			// Получатель мог упасть или оказаться в другой партиции, пока сообщение шло
+			receiver, ok := net.endpoints[to]
+			if ok && !receiver.closed && net.sim.connected(t.id, to) {
+				receiver.handler(msg)
+			}
+		})
+	}
+	return nil
+}
+
+// В симуляции всегда nil: сообщения передаются обработчику из Endpoint
+func (t *SimTransport[M]) Receive() <-chan M {
+	return nil
+}
+
+func (t *SimTransport[M]) Close() error {
+	t.closed = true
+	return nil
+}
+
+// Кластер Raft в симуляции. Каждый узел хранит состояние в MemoryStorage,
+// которое переживает Crash: Restart создаёт узел заново через NewNode.
+type SimRaftCluster struct {
+	Sim      *Simulation
+	Nodes    map[int]*Node
+	Storages map[int]*MemoryStorage
+	ids      []int
+	network  *SimNetwork[MessageRaft]
+	clients  *SimNetwork[kvMessage] // Запросы клиентов хранилища и ответы на них
+	// Вызывается для каждого созданного узла до запуска: задать StateMachine,
+	// SnapshotThreshold и т.д.
+	configure func(n *Node)
+}
+
+func NewSimRaftCluster(sim *Simulation, ids []int, configure func(n *Node)) *SimRaftCluster {
+	c := &SimRaftCluster{
+		Sim:       sim,
+		Nodes:     make(map[int]*Node),
+		Storages:  make(map[int]*MemoryStorage),
+		ids:       append([]int(nil), ids...),
+		network:   NewSimNetwork[MessageRaft](sim),
+		clients:   NewSimNetwork[kvMessage](sim),
+		configure: configure,
+	}
+	sort.Ints(c.ids)
+	for _, id := range c.ids {
+		c.Storages[id] = NewMemoryStorage()
+		sim.register(id, func() { c.crash(id) }, func() { c.start(id) })
+	}
+	for _, id := range c.ids {
+		c.start(id)
+	}
+	return c
+}
+
+func (c *SimRaftCluster) start(id int) {
+	// Загрузка из MemoryStorage не может завершиться ошибкой
+	node, _ := NewNode(id, filter(c.ids, id), c.Storages[id])
+	node.rand = rand.New(rand.NewSource(c.Sim.rand.Int63()))
+	node.Logger = c.Sim.Logf
+	if c.configure != nil {
+		c.configure(node)
+	}
+	c.Nodes[id] = node
+	var endpoint *SimTransport[kvMessage]
+	endpoint = c.clients.Endpoint(id, func(msg kvMessage) { serveKV(node, endpoint, msg) })
+	node.start(c.network.Endpoint(id, node.step), c.Sim.clock(id))
+}
+
+func (c *SimRaftCluster) crash(id int) {
+	c.Nodes[id].stop()
+	c.network.endpoints[id].Close()
+	c.clients.endpoints[id].Close()
+}
+
+// Лидер с наибольшим сроком среди работающих узлов
+func (c *SimRaftCluster) Leader() (int, bool) {
+	leader, term := -1, -1
+	for _, id := range c.ids {
+		if c.Sim.Crashed(id) {
+			continue
+		}
+		node := c.Nodes[id]
+		node.mutex.Lock()
+		if node.State == Leader && node.CurrentTerm > term {
+			leader, term = id, node.CurrentTerm
+		}
+		node.mutex.Unlock()
+	}
+	return leader, leader != -1
+}
+
+// Клиент хранилища ключ-значение в симуляции. Повторяет поведение KVClient,
+// но не блокируется: результат передаётся в done в ходе симуляции. Запросы
+// и ответы идут через сеть симуляции, поэтому теряются, задерживаются
+// и не проходят через партиции так же, как сообщения Raft. В сети клиент
+// занимает адрес Addr; чтобы клиент оказался в одной партиции с узлами,
+// его адрес передают в Partition вместе с ними, иначе при партиции клиент
+// изолирован. Не дождавшись ответа за kvRetryTimeout, клиент повторяет
+// запрос на другом узле. Одновременно у клиента может выполняться только
+// одна операция.
+type SimKVClient struct {
+	cluster  *SimRaftCluster
+	endpoint *SimTransport[kvMessage]
+	clientID int64
+	seq      int64
+	leader   int
+	attempt  int // Номер текущей попытки: ответы и таймауты прежних попыток игнорируются
+
+	// Текущая операция
+	req  ClientRequest
+	done func(KVResult)
+	next int // Следующий узел для перебора, пока лидер неизвестен
+}
+
+// Запрос клиента узлу или ответ узла клиенту
+type kvMessage struct {
+	From    int
+	Attempt int
+	Request ClientRequest
+	Result  KVResult
+	Err     error
+}
+
+// Сколько клиент ждёт ответа узла, прежде чем повторить запрос
+const kvRetryTimeout = 200 * time.Millisecond
+
+// Адрес клиента clientID в сети симуляции. Адреса клиентов отрицательны,
+// поэтому не пересекаются с ID узлов.
+func kvClientAddr(clientID int64) int {
+	return -1 - int(clientID)
+}
+
+func (c *SimRaftCluster) NewKVClient(clientID int64) *SimKVClient {
+	client := &SimKVClient{cluster: c, clientID: clientID, leader: -1}
+	client.endpoint = c.clients.Endpoint(kvClientAddr(clientID), client.receive)
+	return client
+}
+
+// Адрес клиента в сети симуляции
+func (c *SimKVClient) Addr() int {
+	return c.endpoint.id
+}
+
+// Выполняет команду; done вызывается один раз, когда она применена
+func (c *SimKVClient) Do(cmd KVCommand, done func(KVResult)) {
+	c.seq++
+	c.req = ClientRequest{ClientID: c.clientID, Seq: c.seq, Command: cmd}
+	c.done, c.next = done, 0
+	c.try()
+}
+
+func (c *SimKVClient) try() {
+	target := c.leader
+	if _, ok := c.cluster.Nodes[target]; !ok {
+		// Лидер неизвестен — перебираем узлы по кругу
+		target = c.cluster.ids[c.next%len(c.cluster.ids)]
+		c.next++
+	}
+	c.attempt++
+	attempt := c.attempt
+	c.endpoint.Send(target, kvMessage{From: c.Addr(), Attempt: attempt, Request: c.req})
+	c.cluster.Sim.AfterFunc(kvRetryTimeout, func() {
+		if attempt == c.attempt {
+			// Запрос или ответ потерялся, узел упал или потерял лидерство:
+			// повторяем с тем же Seq
+			c.leader = -1
+			c.try()
+		}
+	})
+}
+
+func (c *SimKVClient) retry(delay time.Duration) {
+	c.cluster.Sim.AfterFunc(delay, c.try)
+}
+
+// Ответ узла. Копии и опоздавшие ответы прежних попыток отбрасываются.
+func (c *SimKVClient) receive(msg kvMessage) {
+	if msg.Attempt != c.attempt {
+		return
+	}
+	c.attempt++
+
+	var notLeader *NotLeaderError
+	switch {
+	case msg.Err == nil:
+		c.leader = msg.From
+		c.done(msg.Result)
+	case errors.As(msg.Err, &notLeader) && notLeader.LeaderID != msg.From && notLeader.LeaderID != -1:
+		c.leader = notLeader.LeaderID
+		c.retry(time.Millisecond)
+	default:
+		c.leader = -1
+		c.retry(10 * time.Millisecond)
+	}
+}
+
+// Принимает запрос клиента на узле и отвечает, когда команда применена
+// или отклонена. notify вызывается под mutex узла, но Send только
+// планирует доставку, поэтому ответ можно отправить прямо из него.
+func serveKV(node *Node, endpoint *SimTransport[kvMessage], msg kvMessage) {
+	reply := kvMessage{From: node.ID, Attempt: msg.Attempt}
+	node.mutex.Lock()
+	_, _, err := node.submit(msg.Request, func(res applyResult) {
+		reply.Result, _ = res.result.(KVResult)
+		reply.Err = res.err
+		endpoint.Send(msg.From, reply)
+	})
+	node.mutex.Unlock()
+	if err != nil {
+		reply.Err = err
+		endpoint.Send(msg.From, reply)
+	}
+}
+
+// Узлы Bully в симуляции. После Restart узел забывает лидера
+// и через 3 секунды начинает новые выборы.
+func NewSimBully(sim *Simulation, ids []int) map[int]*BullyNode {
+	network := NewSimNetwork[Msg](sim)
+	nodes := make(map[int]*BullyNode)
+	for _, id := range ids {
+		nodes[id] = NewBullyNode(id)
+		nodes[id].Logger = sim.Logf
+	}
+	for _, id := range ids {
+		node := nodes[id]
+		attach := func() {
+			node.LeaderID = -1
+			node.HasNeighbour = true
+			node.attach(network.Endpoint(id, node.Handle), sim.clock(id), filter(ids, id))
+		}
+		sim.register(id, func() { network.endpoints[id].Close() }, attach)
+		attach()
+	}
+	return nodes
+}
+
+// Кольцо в симуляции в порядке ids. Упавший узел рвёт кольцо: у алгоритма
+// нет обнаружения сбоев, неактивным он считает только узел с Alive = false.
+func NewSimRing(sim *Simulation, ids []int) map[int]*RingNode {
+	network := NewSimNetwork[Message](sim)
+	nodes := make(map[int]*RingNode)
+	for _, id := range ids {
+		nodes[id] = NewRingNode(id)
+		nodes[id].Logger = sim.Logf
+	}
+	for i, id := range ids {
+		node, next := nodes[id], ids[(i+1)%len(ids)]
+		attach := func() {
+			node.LeaderID = -1
+			node.attach(network.Endpoint(id, node.Handle), next, ids)
+		}
+		sim.register(id, func() { network.endpoints[id].Close() }, attach)
+		attach()
+	}
+	return nodes
+}
@@ -0,0 +1,60 @@
+package distributed
+
+import (
+	"wintersc/graph"
+	"wintersc/storage"
+)
+
+// Детерминированный автомат, реплицируемый через Raft. Каждый узел применяет
+// к своему экземпляру одни и те же команды в одном и том же порядке, поэтому
+// состояния всех реплик совпадают. Результат Apply возвращается тому,
+// кто предложил команду.
+//
+// Snapshot возвращает состояние после всех применённых команд, Restore
+// полностью заменяет состояние снимком. По ним узел уплотняет лог и догоняет
+// отставшие реплики.
+type StateMachine interface {
+	Apply(command interface{}) interface{}
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// Реплицируемый граф: команды — операции журнала storage.Record.
+// Читать граф можно через Graph().Snapshot() параллельно с применением.
+type GraphStateMachine struct {
+	graph *graph.ConcurrentGraph
+}
+
+func NewGraphStateMachine() *GraphStateMachine {
+	return &GraphStateMachine{graph: graph.NewConcurrentGraph()}
+}
+
+func (m *GraphStateMachine) Graph() *graph.ConcurrentGraph {
+	return m.graph
+}
+
+// Возвращает ошибку применения операции или nil
+func (m *GraphStateMachine) Apply(command interface{}) interface{} {
+	record, ok := command.(storage.Record)
+	if !ok {
+		return storage.ErrUnknown
+	}
+	var err error
+	m.graph.Update(func(g *graph.Graph) { err = record.Apply(g) })
+	return err
+}
+
+func (m *GraphStateMachine) Snapshot() ([]byte, error) {
+	var data []byte
+	m.graph.Read(func(g *graph.Graph) { data = storage.MarshalGraph(g) })
+	return data, nil
+}
+
+func (m *GraphStateMachine) Restore(data []byte) error {
+	restored, err := storage.UnmarshalGraph(data)
+	if err != nil {
+		return err
+	}
+	m.graph.Update(func(g *graph.Graph) { *g = *restored })
+	return nil
+}
@@ -0,0 +1,270 @@
+package distributed
+
+import (
+	"encoding/gob"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Сеть с точки зрения одного узла: отправка сообщений типа M другим узлам
+// по ID и очередь входящих. Send никогда не блокируется: если очередь
+// получателя полна или он недоступен, сообщение теряется. Raft и выборы
+// сами повторяют отправку, поэтому потеря равносильна сбою сети.
+type Transport[M any] interface {
+	Send(to int, msg M) error
+	// Входящие сообщения; канал закрывается после Close
+	Receive() <-chan M
+	Close() error
+}
+
+var (
+	ErrUnknownPeer = errors.New("transport: unknown peer")
+	ErrQueueFull   = errors.New("transport: queue is full")
+	ErrClosed      = errors.New("transport: closed")
+)
+
+// Сеть внутри процесса: каждый узел получает свою точку подключения Endpoint
+type MemoryNetwork[M any] struct {
+	mutex     sync.Mutex
+	buffer    int
+	endpoints map[int]*MemoryTransport[M]
+}
+
+// buffer — размер входящей очереди каждого узла
+func NewMemoryNetwork[M any](buffer int) *MemoryNetwork[M] {
+	return &MemoryNetwork[M]{buffer: buffer, endpoints: make(map[int]*MemoryTransport[M])}
+}
+
+// Точка подключения узла id; создаётся при первом обращении
+func (net *MemoryNetwork[M]) Endpoint(id int) *MemoryTransport[M] {
+	net.mutex.Lock()
+	defer net.mutex.Unlock()
+	if t, ok := net.endpoints[id]; ok {
+		return t
+	}
+	t := &MemoryTransport[M]{id: id, network: net, inbox: make(chan M, net.buffer)}
+	net.endpoints[id] = t
+	return t
+}
+
+type MemoryTransport[M any] struct {
+	id      int
+	network *MemoryNetwork[M]
+	inbox   chan M
+}
+
+func (t *MemoryTransport[M]) Send(to int, msg M) error {
+	// Отправка под mutex сети, чтобы получатель не закрыл очередь посреди неё
+	t.network.mutex.Lock()
+	defer t.network.mutex.Unlock()
+	if t.network.endpoints[t.id] != t {
+		return ErrClosed
+	}
+	peer, ok := t.network.endpoints[to]
+	if !ok {
+		return ErrUnknownPeer
+	}
+	select {
+	case peer.inbox <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (t *MemoryTransport[M]) Receive() <-chan M {
+	return t.inbox
+}
+
+// Отключает узел от сети. Повторный Endpoint с тем же id создаст новую точку.
+func (t *MemoryTransport[M]) Close() error {
+	t.network.mutex.Lock()
+	defer t.network.mutex.Unlock()
+	if t.network.endpoints[t.id] != t {
+		return nil
+	}
+	delete(t.network.endpoints, t.id)
+	close(t.inbox)
+	return nil
+}
+
+// Размер входящей очереди и очереди отправки каждому узлу в TCPTransport
+const tcpQueueSize = 256
+
+// Транспорт поверх TCP: узлы — отдельные процессы, адреса которых заданы
+// заранее. Сообщения кодируются потоком gob, по одному соединению на
+// направление. Соединение с узлом устанавливается при первой отправке и
+// переустанавливается после ошибки; сообщения, которые не удалось
+// отправить, теряются. Конкретные типы в полях interface{} (например,
+// команды в LogEntry) должны быть зарегистрированы через gob.Register.
+type TCPTransport[M any] struct {
+	id       int
+	addrs    map[int]string
+	listener net.Listener
+	inbox    chan M
+	closed   chan struct{}
+
+	mutex   sync.Mutex
+	queues  map[int]chan M
+	conns   map[net.Conn]struct{} // Входящие соединения, закрываются в Close
+	readers sync.WaitGroup
+	writers sync.WaitGroup
+	once    sync.Once
+}
+
+// Начинает слушать addrs[id]. Остальные адреса — узлы, которым можно отправлять.
+func NewTCPTransport[M any](id int, addrs map[int]string) (*TCPTransport[M], error) {
+	listener, err := net.Listen("tcp", addrs[id])
+	if err != nil {
+		return nil, err
+	}
+	t := &TCPTransport[M]{
+		id:       id,
+		addrs:    addrs,
+		listener: listener,
+		inbox:    make(chan M, tcpQueueSize),
+		closed:   make(chan struct{}),
+		queues:   make(map[int]chan M),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	t.readers.Add(1)
+	go t.accept()
+	return t, nil
+}
+
+// Адрес, на котором транспорт принимает соединения (полезно при порте :0)
+func (t *TCPTransport[M]) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
+func (t *TCPTransport[M]) Send(to int, msg M) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	select {
+	case <-t.closed:
+		return ErrClosed
+	default:
+	}
+
+	queue, ok := t.queues[to]
+	if !ok {
+		addr, known := t.addrs[to]
+		if !known {
+			return ErrUnknownPeer
+		}
+		queue = make(chan M, tcpQueueSize)
+		t.queues[to] = queue
+		t.writers.Add(1)
+		go t.write(addr, queue)
+	}
+	select {
+	case queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (t *TCPTransport[M]) Receive() <-chan M {
+	return t.inbox
+}
+
+func (t *TCPTransport[M]) Close() error {
+	var err error
+	t.once.Do(func() {
+		t.mutex.Lock()
+		close(t.closed)
+		err = t.listener.Close()
+		for conn := range t.conns {
+			conn.Close()
+		}
+		t.mutex.Unlock()
+
+		t.writers.Wait()
+		t.readers.Wait()
+		close(t.inbox)
+	})
+	return err
+}
+
+// Отправляет сообщения из очереди одному узлу, переподключаясь после ошибок
+func (t *TCPTransport[M]) write(addr string, queue chan M) {
+	defer t.writers.Done()
+	var conn net.Conn
+	var encoder *gob.Encoder
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		var msg M
+		select {
+		case <-t.closed:
+			return
+		case msg = <-queue:
+		}
+
+		if conn == nil {
+			c, err := net.DialTimeout("tcp", addr, time.Second)
+			if err != nil {
+				continue // Узел недоступен: сообщение теряется
+			}
+			conn, encoder = c, gob.NewEncoder(c)
+		}
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if err := encoder.Encode(&msg); err != nil {
+			// Поток gob после ошибки непригоден, начинаем новое соединение
+			conn.Close()
+			conn, encoder = nil, nil
+		}
+	}
+}
+
+func (t *TCPTransport[M]) accept() {
+	defer t.readers.Done()
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return // Listener закрыт в Close
+		}
+		t.mutex.Lock()
+		select {
+		case <-t.closed:
+			t.mutex.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		t.conns[conn] = struct{}{}
+		t.readers.Add(1)
+		t.mutex.Unlock()
+		go t.read(conn)
+	}
+}
+
+func (t *TCPTransport[M]) read(conn net.Conn) {
+	defer t.readers.Done()
+	defer func() {
+		t.mutex.Lock()
+		delete(t.conns, conn)
+		t.mutex.Unlock()
+		conn.Close()
+	}()
+
+	decoder := gob.NewDecoder(conn)
+	for {
+		var msg M
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+		select {
+		case t.inbox <- msg:
+		case <-t.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package distributed
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type tcpTestMessage struct {
+	From, Seq int
+	Text      string
+}
+
+// Транспорт узла id на свободном порту localhost; peers — адреса остальных
+func listenTCP(t *testing.T, id int, addr string, peers map[int]string) *TCPTransport[tcpTestMessage] {
+	t.Helper()
+	addrs := map[int]string{id: addr}
+	for peer, a := range peers {
+		addrs[peer] = a
+	}
+	transport, err := NewTCPTransport[tcpTestMessage](id, addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport
+}
+
+func receive(t *testing.T, transport *TCPTransport[tcpTestMessage]) tcpTestMessage {
+	t.Helper()
+	select {
+	case msg, ok := <-transport.Receive():
+		if !ok {
+			t.Fatal("очередь входящих закрыта")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("сообщение не пришло за 5 секунд")
+	}
+	return tcpTestMessage{}
+}
+
+func TestTCPTransport(t *testing.T) {
+	b := listenTCP(t, 2, "127.0.0.1:0", nil)
+	addrB := b.Addr().String()
+	a := listenTCP(t, 1, "127.0.0.1:0", map[int]string{2: addrB})
+	defer a.Close()
+
+	// Очередь отправки сохраняет порядок, gob передаёт все поля
+	const count = 100
+	for i := 0; i < count; i++ {
+		if err := a.Send(2, tcpTestMessage{From: 1, Seq: i, Text: "привет"}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+	for i := 0; i < count; i++ {
+		if msg := receive(t, b); msg != (tcpTestMessage{From: 1, Seq: i, Text: "привет"}) {
+			t.Fatalf("сообщение %d: %+v", i, msg)
+		}
+	}
+	if err := a.Send(3, tcpTestMessage{}); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Send неизвестному узлу: %v", err)
+	}
+
+	// Получатель перезапускается на том же адресе: отправитель замечает
+	// разорванное соединение и подключается заново. Сообщения, отправленные
+	// в разорванное соединение, теряются, поэтому шлём, пока одно не дойдёт.
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, ok := <-b.Receive(); ok {
+		t.Error("после Close очередь входящих не закрыта")
+	}
+	b = listenTCP(t, 2, addrB, nil)
+	defer b.Close()
+	deadline := time.After(10 * time.Second)
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for seq := count; ; seq++ {
+		a.Send(2, tcpTestMessage{From: 1, Seq: seq})
+		select {
+		case msg := <-b.Receive():
+			if msg.From != 1 || msg.Seq < count {
+				t.Fatalf("после перезапуска пришло %+v", msg)
+			}
+		case <-ticker.C:
+			continue
+		case <-deadline:
+			t.Fatal("отправитель не переподключился к перезапущенному узлу")
+		}
+		break
+	}
+
+	// Close идемпотентен и не ждёт вечно входящих соединений
+	closed := make(chan error)
+	go func() { closed <- b.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close завис при открытом входящем соединении")
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("повторный Close: %v", err)
+	}
+	if err := b.Send(1, tcpTestMessage{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send после Close: %v", err)
+	}
+}
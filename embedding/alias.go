@@ -0,0 +1,71 @@
+package embedding
+
+// Таблица псевдонимов (метод Vose): выбор из дискретного распределения за O(1)
+type aliasTable struct {
+	prob  []float64
+	alias []int32
+}
+
+func newAliasTable(weights []float64) aliasTable {
+	n := len(weights)
+	t := aliasTable{prob: make([]float64, n), alias: make([]int32, n)}
+	if n == 0 {
+		return t
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	scaled := make([]float64, n)
+	small, large := []int{}, []int{}
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		t.prob[s] = scaled[s]
+		t.alias[s] = int32(l)
+		scaled[l] -= 1 - scaled[s]
+		if scaled[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// Остатки из-за погрешности округления считаем равными единице
+	for _, i := range append(small, large...) {
+		t.prob[i] = 1
+	}
+	return t
+}
+
+func (t aliasTable) sample(rng *splitmix) int {
+	i := int(rng.next() % uint64(len(t.prob)))
+	if rng.float64() < t.prob[i] {
+		return i
+	}
+	return int(t.alias[i])
+}
+
+// Маленький генератор splitmix64. У каждого блуждания свой генератор,
+// поэтому результат не зависит от числа горутин.
+type splitmix uint64
+
+func (s *splitmix) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	x := uint64(*s)
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+func (s *splitmix) float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
@@ -0,0 +1,208 @@
+package embedding
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"wintersc/graph"
+)
+
+// Параметры блужданий node2vec. При P = Q = 1 получается DeepWalk.
+type WalkOptions struct {
+	P            float64 // Параметр возврата (0 — 1)
+	Q            float64 // Параметр «внутрь-наружу» (0 — 1)
+	WalkLength   int     // Длина блуждания в вершинах (0 — 80)
+	WalksPerNode int     // Блужданий из каждой вершины (0 — 10)
+	Workers      int     // Число горутин (0 — по числу CPU)
+	Seed         int64
+}
+
+// Сколько блужданий считается параллельно перед записью
+const walkBatch = 4096
+
+// Подготовленный граф: плотные индексы, отсортированные соседи и таблицы псевдонимов.
+// Таблиц второго порядка на каждую пару (prev, cur) нет: они заняли бы
+// O(Σ deg²) памяти, поэтому шаг второго порядка выбирается с отклонением.
+type walker struct {
+	opts      WalkOptions
+	ids       []int
+	neighbors [][]int32
+	weighted  []aliasTable // Сосед пропорционально весу ребра
+	maxAlpha  float64      // Наибольший множитель смещения: max(1/P, 1, 1/Q)
+}
+
+func (o *WalkOptions) defaults() {
+	if o.P <= 0 {
+		o.P = 1
+	}
+	if o.Q <= 0 {
+		o.Q = 1
+	}
+	if o.WalkLength <= 0 {
+		o.WalkLength = 80
+	}
+	if o.WalksPerNode <= 0 {
+		o.WalksPerNode = 10
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+}
+
+func newWalker(g graph.View, opts WalkOptions) *walker {
+	w := &walker{opts: opts, maxAlpha: max(1, 1/opts.P, 1/opts.Q)}
+	for u := range g.Vertices() {
+		w.ids = append(w.ids, u)
+	}
+	sort.Ints(w.ids)
+	index := make(map[int]int32, len(w.ids))
+	for i, u := range w.ids {
+		index[u] = int32(i)
+	}
+
+	w.neighbors = make([][]int32, len(w.ids))
+	w.weighted = make([]aliasTable, len(w.ids))
+	for i, u := range w.ids {
+		type pair struct {
+			v int32
+			w float64
+		}
+		list := []pair{}
+		for v, weight := range g.Neighbors(u) {
+			// Неположительные веса не задают вероятность, считаем их единичными
+			if weight <= 0 {
+				weight = 1
+			}
+			list = append(list, pair{index[v], float64(weight)})
+		}
+		sort.Slice(list, func(a, b int) bool { return list[a].v < list[b].v })
+		weights := make([]float64, len(list))
+		for k, p := range list {
+			w.neighbors[i] = append(w.neighbors[i], p.v)
+			weights[k] = p.w
+		}
+		w.weighted[i] = newAliasTable(weights)
+	}
+	return w
+}
+
+// Есть ли ребро (t, x): бинарный поиск по отсортированным соседям
+func (w *walker) adjacent(t int, x int32) bool {
+	list := w.neighbors[t]
+	i := sort.Search(len(list), func(i int) bool { return list[i] >= x })
+	return i < len(list) && list[i] == x
+}
+
+// Одно блуждание из вершины start. Генератор зависит только от seed, раунда и вершины.
+func (w *walker) walk(round, start int) []int32 {
+	rng := splitmix(uint64(w.opts.Seed)*0x9e3779b97f4a7c15 ^ uint64(round)<<32 ^ uint64(start))
+	path := make([]int32, 1, w.opts.WalkLength)
+	path[0] = int32(start)
+	for len(path) < w.opts.WalkLength {
+		cur := int(path[len(path)-1])
+		if len(w.neighbors[cur]) == 0 {
+			break
+		}
+		if len(path) == 1 {
+			path = append(path, w.neighbors[cur][w.weighted[cur].sample(&rng)])
+			continue
+		}
+		path = append(path, w.step(int(path[len(path)-2]), cur, &rng))
+	}
+	return path
+}
+
+// Шаг второго порядка из cur, если пришли из prev. Кандидат x выбирается
+// пропорционально весу ребра (cur, x) и принимается с вероятностью
+// α / maxAlpha, где α = 1/P для возврата в prev, 1 для соседей prev и 1/Q
+// для остальных вершин. Итог распределён так же, как в таблице псевдонимов
+// с весами w·α, а при P = Q = 1 кандидат принимается сразу.
+func (w *walker) step(prev, cur int, rng *splitmix) int32 {
+	for {
+		x := w.neighbors[cur][w.weighted[cur].sample(rng)]
+		alpha := 1.0
+		switch {
+		case int(x) == prev:
+			alpha = 1 / w.opts.P
+		case w.adjacent(prev, x):
+		default:
+			alpha = 1 / w.opts.Q
+		}
+		if rng.float64()*w.maxAlpha < alpha {
+			return x
+		}
+	}
+}
+
+// Порождает блуждания node2vec и пишет их в out по одному на строку
+// (ID вершин через пробел) — это корпус для word2vec. Порядок и содержимое
+// зависят только от Seed, но не от числа горутин.
+func GenerateWalks(ctx context.Context, g graph.View, opts WalkOptions, out io.Writer) error {
+	opts.defaults()
+	w := newWalker(g, opts)
+	buf := bufio.NewWriter(out)
+
+	for round := 0; round < opts.WalksPerNode; round++ {
+		// Порядок стартовых вершин в раунде перемешан, но детерминирован
+		rng := splitmix(uint64(opts.Seed) ^ uint64(round+1)*0xbf58476d1ce4e5b9)
+		order := make([]int, len(w.ids))
+		for i := range order {
+			order[i] = i
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			j := int(rng.next() % uint64(i+1))
+			order[i], order[j] = order[j], order[i]
+		}
+
+		for from := 0; from < len(order); from += walkBatch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch := order[from:min(from+walkBatch, len(order))]
+			walks := make([][]int32, len(batch))
+			parallel(len(batch), opts.Workers, func(i int) {
+				walks[i] = w.walk(round, batch[i])
+			})
+
+			var line []byte
+			for _, path := range walks {
+				line = line[:0]
+				for i, v := range path {
+					if i > 0 {
+						line = append(line, ' ')
+					}
+					line = strconv.AppendInt(line, int64(w.ids[v]), 10)
+				}
+				line = append(line, '\n')
+				if _, err := buf.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+// Выполняет fn(i) для i из [0, n) на workers горутинах
+func parallel(n, workers int, fn func(i int)) {
+	var wg sync.WaitGroup
+	next := make(chan int, workers)
+	for k := 0; k < workers; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
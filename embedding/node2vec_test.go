@@ -0,0 +1,86 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// Кольцо из 50 вершин с хордами и изолированная вершина
+func walkGraph() *graph.Graph {
+	g := graph.NewGraph()
+	for u := 0; u < 50; u++ {
+		g.AddEdge(u, (u+1)%50, 1+u%3)
+		if u%5 == 0 {
+			g.AddEdge(u, (u+17)%50, 2)
+		}
+	}
+	g.AddVertex(100)
+	return g
+}
+
+func walks(t *testing.T, g graph.View, opts WalkOptions) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	if err := GenerateWalks(context.Background(), g, opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// Корпус зависит только от Seed, но не от числа горутин
+func TestGenerateWalksReproducible(t *testing.T) {
+	g := walkGraph()
+	opts := WalkOptions{P: 0.5, Q: 2, WalkLength: 20, WalksPerNode: 3, Seed: 11}
+	opts.Workers = 1
+	first := walks(t, g, opts)
+	for _, workers := range []int{1, 4, 16} {
+		opts.Workers = workers
+		if !bytes.Equal(walks(t, g, opts), first) {
+			t.Errorf("Workers = %d: корпус отличается от однопоточного", workers)
+		}
+	}
+	opts.Seed = 12
+	if bytes.Equal(walks(t, g, opts), first) {
+		t.Error("разные Seed дали одинаковый корпус")
+	}
+
+	corpus, err := ReadWalks(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corpus) != 3*g.NumVertices() {
+		t.Fatalf("%d блужданий, ожидалось %d", len(corpus), 3*g.NumVertices())
+	}
+	for _, walk := range corpus {
+		// Из изолированной вершины блуждание не уходит
+		if walk[0] == 100 {
+			if len(walk) != 1 {
+				t.Errorf("блуждание из изолированной вершины: %v", walk)
+			}
+			continue
+		}
+		if len(walk) != 20 {
+			t.Errorf("длина блуждания %d, ожидалось 20", len(walk))
+		}
+		for i := 1; i < len(walk); i++ {
+			if !graph.HasEdge(g, walk[i-1], walk[i]) {
+				t.Fatalf("шаг %d → %d не по ребру", walk[i-1], walk[i])
+			}
+		}
+	}
+}
+
+func TestTrainSkipGramReproducible(t *testing.T) {
+	corpus, err := ReadWalks(bytes.NewReader(walks(t, walkGraph(), WalkOptions{WalkLength: 10, WalksPerNode: 2, Seed: 3})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := SkipGramOptions{Dimensions: 8, Seed: 5}
+	a, b := TrainSkipGram(corpus, opts), TrainSkipGram(corpus, opts)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("два обучения с одним Seed дали разные векторы")
+	}
+}
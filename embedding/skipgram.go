@@ -0,0 +1,206 @@
+package embedding
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Параметры skip-gram с отрицательными примерами (SGNS, как в word2vec)
+type SkipGramOptions struct {
+	Dimensions   int     // Размерность векторов (0 — 128)
+	Window       int     // Наибольшее расстояние до контекстной вершины (0 — 5)
+	Negative     int     // Отрицательных примеров на пару (0 — 5)
+	Epochs       int     // Проходов по корпусу (0 — 1)
+	LearningRate float64 // Начальная скорость обучения (0 — 0.025)
+	Seed         int64
+}
+
+// Векторы вершин: Vectors[i] относится к вершине IDs[i]
+type Embedding struct {
+	IDs     []int
+	Vectors [][]float32
+}
+
+// Размер таблицы для выбора отрицательных примеров
+const unigramTableSize = 1 << 20
+
+func (o *SkipGramOptions) defaults() {
+	if o.Dimensions <= 0 {
+		o.Dimensions = 128
+	}
+	if o.Window <= 0 {
+		o.Window = 5
+	}
+	if o.Negative <= 0 {
+		o.Negative = 5
+	}
+	if o.Epochs <= 0 {
+		o.Epochs = 1
+	}
+	if o.LearningRate <= 0 {
+		o.LearningRate = 0.025
+	}
+}
+
+// Читает корпус блужданий в формате GenerateWalks
+func ReadWalks(r io.Reader) ([][]int, error) {
+	var walks [][]int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1<<16), 1<<26)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		walk := make([]int, len(fields))
+		for i, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, err
+			}
+			walk[i] = v
+		}
+		walks = append(walks, walk)
+	}
+	return walks, scanner.Err()
+}
+
+// Обучает векторы вершин по корпусу блужданий. Обучение однопоточное,
+// поэтому при одинаковом Seed результат совпадает до бита.
+func TrainSkipGram(walks [][]int, opts SkipGramOptions) *Embedding {
+	opts.defaults()
+	rng := splitmix(uint64(opts.Seed))
+
+	// Словарь: ID вершин по возрастанию и частоты
+	counts := make(map[int]int)
+	total := 0
+	for _, walk := range walks {
+		for _, v := range walk {
+			counts[v]++
+			total++
+		}
+	}
+	ids := make([]int, 0, len(counts))
+	for v := range counts {
+		ids = append(ids, v)
+	}
+	sort.Ints(ids)
+	index := make(map[int]int, len(ids))
+	for i, v := range ids {
+		index[v] = i
+	}
+
+	dims := opts.Dimensions
+	input := make([]float32, len(ids)*dims)
+	output := make([]float32, len(ids)*dims)
+	for i := range input {
+		input[i] = float32((rng.float64() - 0.5) / float64(dims))
+	}
+
+	// Отрицательные примеры выбираются пропорционально частоте в степени 3/4
+	table := make([]int32, 0, unigramTableSize)
+	norm := 0.0
+	for _, v := range ids {
+		norm += math.Pow(float64(counts[v]), 0.75)
+	}
+	cumulative := 0.0
+	for i, v := range ids {
+		cumulative += math.Pow(float64(counts[v]), 0.75) / norm
+		for len(table) < unigramTableSize && float64(len(table)) < cumulative*unigramTableSize {
+			table = append(table, int32(i))
+		}
+	}
+	for len(table) < unigramTableSize {
+		table = append(table, int32(len(ids)-1))
+	}
+
+	grad := make([]float32, dims)
+	steps, processed := float64(opts.Epochs*total), 0.0
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		for _, walk := range walks {
+			for pos, v := range walk {
+				// Скорость обучения линейно убывает до 0.01% от начальной
+				lr := float32(opts.LearningRate * max(1e-4, 1-processed/steps))
+				processed++
+
+				center := index[v]
+				window := 1 + int(rng.next()%uint64(opts.Window))
+				for c := max(0, pos-window); c <= min(len(walk)-1, pos+window); c++ {
+					if c == pos {
+						continue
+					}
+					in := input[index[walk[c]]*dims:][:dims]
+					clear(grad)
+					for k := 0; k <= opts.Negative; k++ {
+						target, label := center, float32(1)
+						if k > 0 {
+							target = int(table[rng.next()%unigramTableSize])
+							if target == center {
+								continue
+							}
+							label = 0
+						}
+						out := output[target*dims:][:dims]
+						dot := float32(0)
+						for d := range in {
+							dot += in[d] * out[d]
+						}
+						g := (label - sigmoid(dot)) * lr
+						for d := range in {
+							grad[d] += g * out[d]
+							out[d] += g * in[d]
+						}
+					}
+					for d := range in {
+						in[d] += grad[d]
+					}
+				}
+			}
+		}
+	}
+
+	e := &Embedding{IDs: ids, Vectors: make([][]float32, len(ids))}
+	for i := range ids {
+		e.Vectors[i] = input[i*dims : (i+1)*dims]
+	}
+	return e
+}
+
+func sigmoid(x float32) float32 {
+	if x > 6 {
+		return 1
+	}
+	if x < -6 {
+		return 0
+	}
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
+
+// Записывает векторы в текстовом формате word2vec:
+// заголовок "<число вершин> <размерность>", затем "<ID> <координаты...>"
+func (e *Embedding) WriteWord2Vec(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	dims := 0
+	if len(e.Vectors) > 0 {
+		dims = len(e.Vectors[0])
+	}
+	fmt.Fprintf(buf, "%d %d\n", len(e.IDs), dims)
+	var line []byte
+	for i, id := range e.IDs {
+		line = strconv.AppendInt(line[:0], int64(id), 10)
+		for _, x := range e.Vectors[i] {
+			line = append(line, ' ')
+			line = strconv.AppendFloat(line, float64(x), 'f', 6, 32)
+		}
+		line = append(line, '\n')
+		if _, err := buf.Write(line); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
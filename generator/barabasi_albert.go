@@ -0,0 +1,41 @@
+package generator
+
+import "wintersc/graph"
+
+// Модель Барабаши–Альберт: начинаем с полного графа на m+1 вершинах,
+// каждая новая вершина соединяется с m различными существующими,
+// выбранными с вероятностью, пропорциональной степени.
+func BarabasiAlbert(n, m int, opts Options) *graph.Graph {
+	b := newBuilder(n, opts)
+	if m < 1 {
+		return b.graph
+	}
+
+	// Каждая вершина встречается здесь столько раз, какова её степень,
+	// поэтому равномерный выбор из списка — выбор пропорционально степени
+	repeated := []int{}
+	start := min(m+1, n)
+	for u := 0; u < start; u++ {
+		for v := u + 1; v < start; v++ {
+			b.add(u, v)
+			repeated = append(repeated, u, v)
+		}
+	}
+
+	for u := start; u < n; u++ {
+		targets := make(map[int]bool, m)
+		order := []int{} // Порядок выбора, чтобы результат не зависел от обхода map
+		for len(targets) < min(m, u) {
+			v := repeated[b.rng.Intn(len(repeated))]
+			if !targets[v] {
+				targets[v] = true
+				order = append(order, v)
+			}
+		}
+		for _, v := range order {
+			b.add(u, v)
+			repeated = append(repeated, u, v)
+		}
+	}
+	return b.graph
+}
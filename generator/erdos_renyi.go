@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"math"
+	"wintersc/graph"
+)
+
+// Граф Эрдёша–Реньи G(n, p): каждое из n(n-1)/2 рёбер присутствует
+// независимо с вероятностью p. Пропуски между рёбрами выбираются
+// геометрическим распределением, поэтому время работы O(n + m).
+func ErdosRenyiGNP(n int, p float64, opts Options) *graph.Graph {
+	b := newBuilder(n, opts)
+	if p <= 0 || n < 2 {
+		return b.graph
+	}
+	if p >= 1 {
+		for u := 0; u < n; u++ {
+			for v := u + 1; v < n; v++ {
+				b.add(u, v)
+			}
+		}
+		return b.graph
+	}
+
+	// Алгоритм Batagelj–Brandes: перебираем пары (v, w), w < v
+	logq := math.Log(1 - p)
+	v, w := 1, -1
+	for v < n {
+		w += 1 + int(math.Log(1-b.rng.Float64())/logq)
+		for w >= v && v < n {
+			w -= v
+			v++
+		}
+		if v < n {
+			b.add(v, w)
+		}
+	}
+	return b.graph
+}
+
+// Граф Эрдёша–Реньи G(n, m): ровно m различных рёбер, выбранных равновероятно
+func ErdosRenyiGNM(n, m int, opts Options) *graph.Graph {
+	b := newBuilder(n, opts)
+	maxEdges := n * (n - 1) / 2
+	m = min(m, maxEdges)
+
+	// Для плотных графов проще выбрать рёбра, которых не будет
+	if m > maxEdges/2 {
+		skip := make(map[[2]int]bool)
+		for len(skip) < maxEdges-m {
+			u, v := b.rng.Intn(n), b.rng.Intn(n)
+			if u != v {
+				skip[key(u, v)] = true
+			}
+		}
+		for u := 0; u < n; u++ {
+			for v := u + 1; v < n; v++ {
+				if !skip[key(u, v)] {
+					b.add(u, v)
+				}
+			}
+		}
+		return b.graph
+	}
+
+	for len(b.edges) < m {
+		b.add(b.rng.Intn(n), b.rng.Intn(n))
+	}
+	return b.graph
+}
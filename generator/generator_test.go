@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// Все генераторы с одинаковыми параметрами
+var generators = map[string]func(opts Options) *graph.Graph{
+	"ErdosRenyiGNP":  func(opts Options) *graph.Graph { return ErdosRenyiGNP(200, 0.05, opts) },
+	"ErdosRenyiGNM":  func(opts Options) *graph.Graph { return ErdosRenyiGNM(200, 500, opts) },
+	"BarabasiAlbert": func(opts Options) *graph.Graph { return BarabasiAlbert(200, 3, opts) },
+	"WattsStrogatz":  func(opts Options) *graph.Graph { return WattsStrogatz(200, 6, 0.3, opts) },
+	"StochasticBlockModel": func(opts Options) *graph.Graph {
+		g, _ := StochasticBlockModel([]int{50, 70, 80}, [][]float64{
+			{0.2, 0.01, 0.02},
+			{0.01, 0.15, 0.01},
+			{0.02, 0.01, 0.1},
+		}, opts)
+		return g
+	},
+	"RMAT": func(opts Options) *graph.Graph { return RMAT(8, 600, 0.57, 0.19, 0.19, 0.05, opts) },
+}
+
+// Одинаковый Seed даёт тот же граф вплоть до порядка рёбер и весов
+func TestSameSeedSameGraph(t *testing.T) {
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{Seed: 42, Weight: UniformWeight(1, 100)}
+			a, b := generate(opts), generate(opts)
+			if a.NumEdges() == 0 {
+				t.Fatal("сгенерирован граф без рёбер")
+			}
+			if !reflect.DeepEqual(a.Edge, b.Edge) || !reflect.DeepEqual(a.Adj, b.Adj) {
+				t.Error("два запуска с одним Seed дали разные графы")
+			}
+			if other := generate(Options{Seed: 43, Weight: UniformWeight(1, 100)}); reflect.DeepEqual(a.Edge, other.Edge) {
+				t.Error("разные Seed дали одинаковые графы")
+			}
+		})
+	}
+}
+
+func TestUniformWeight(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, bounds := range [][2]int{{3, 7}, {7, 3}, {5, 5}, {-2, 2}} {
+		lo, hi := min(bounds[0], bounds[1]), max(bounds[0], bounds[1])
+		weight := UniformWeight(bounds[0], bounds[1])
+		seen := map[int]bool{}
+		for i := 0; i < 1000; i++ {
+			w := weight(r)
+			if w < lo || w > hi {
+				t.Fatalf("UniformWeight(%d, %d) вернул %d", bounds[0], bounds[1], w)
+			}
+			seen[w] = true
+		}
+		if len(seen) != hi-lo+1 {
+			t.Errorf("UniformWeight(%d, %d): встретилось %d значений из %d", bounds[0], bounds[1], len(seen), hi-lo+1)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package generator
+
+import "wintersc/graph"
+
+// Стохастический граф Кронекера: матрица инициатора возводится в k-ю
+// кронекерову степень, и рёбра «бросаются» по ячейкам пропорционально
+// вероятностям на каждом уровне. Вершин получается len(initiator)^k.
+// Петли и повторные рёбра отбрасываются, поэтому рёбер может выйти меньше m.
+func Kronecker(initiator [][]float64, k, m int, opts Options) *graph.Graph {
+	size := len(initiator)
+	n := 1
+	for i := 0; i < k; i++ {
+		n *= size
+	}
+	b := newBuilder(n, opts)
+
+	total := 0.0
+	cells := []float64{}
+	for _, row := range initiator {
+		for _, p := range row {
+			total += p
+			cells = append(cells, total)
+		}
+	}
+	if total <= 0 {
+		return b.graph
+	}
+
+	// Ограничиваем число попыток, чтобы не зациклиться на плотных матрицах
+	for attempts := 0; len(b.edges) < m && attempts < 10*m; attempts++ {
+		u, v := 0, 0
+		for level := 0; level < k; level++ {
+			x := b.rng.Float64() * total
+			cell := 0
+			for cell < len(cells)-1 && x >= cells[cell] {
+				cell++
+			}
+			u = u*size + cell/size
+			v = v*size + cell%size
+		}
+		b.add(u, v)
+	}
+	return b.graph
+}
+
+// R-MAT: граф Кронекера с инициатором 2×2 [[a, b], [c, d]] на 2^scale вершинах
+func RMAT(scale, m int, a, b, c, d float64, opts Options) *graph.Graph {
+	return Kronecker([][]float64{{a, b}, {c, d}}, scale, m, opts)
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"wintersc/graph"
+)
+
+// Распределение весов рёбер
+type WeightFunc func(r *rand.Rand) int
+
+// Общие параметры генераторов. Одинаковый Seed даёт одинаковый граф.
+type Options struct {
+	Seed   int64
+	Weight WeightFunc // nil — все веса равны 1
+}
+
+func ConstantWeight(w int) WeightFunc {
+	return func(*rand.Rand) int { return w }
+}
+
+// Равномерный вес из [min, max]. Границы в обратном порядке меняются местами.
+func UniformWeight(min, max int) WeightFunc {
+	if max < min {
+		min, max = max, min
+	}
+	return func(r *rand.Rand) int { return min + r.Intn(max-min+1) }
+}
+
+// Экспоненциальное распределение со средним mean, округлённое вверх (не меньше 1)
+func ExponentialWeight(mean float64) WeightFunc {
+	return func(r *rand.Rand) int { return max(1, int(math.Ceil(r.ExpFloat64()*mean))) }
+}
+
+// Нормальное распределение, обрезанное снизу единицей
+func NormalWeight(mean, stddev float64) WeightFunc {
+	return func(r *rand.Rand) int {
+		return max(1, int(math.Round(r.NormFloat64()*stddev+mean)))
+	}
+}
+
+// Состояние одного запуска генератора
+type builder struct {
+	rng    *rand.Rand
+	weight WeightFunc
+	graph  *graph.Graph
+	edges  map[[2]int]bool
+}
+
+func newBuilder(n int, opts Options) *builder {
+	b := &builder{
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+		weight: opts.Weight,
+		graph:  graph.NewGraph(),
+		edges:  make(map[[2]int]bool),
+	}
+	if b.weight == nil {
+		b.weight = ConstantWeight(1)
+	}
+	// Изолированные вершины тоже должны быть в графе
+	for u := 0; u < n; u++ {
+		b.graph.AddVertex(u)
+	}
+	return b
+}
+
+// Добавляет ребро (u, v), если это не петля и не повтор
+func (b *builder) add(u, v int) bool {
+	if u == v || b.has(u, v) {
+		return false
+	}
+	b.edges[key(u, v)] = true
+	b.graph.AddEdge(u, v, b.weight(b.rng))
+	return true
+}
+
+func (b *builder) has(u, v int) bool {
+	return b.edges[key(u, v)]
+}
+
+func key(u, v int) [2]int {
+	if u > v {
+		u, v = v, u
+	}
+	return [2]int{u, v}
+}
@@ -0,0 +1,30 @@
+package generator
+
+import "wintersc/graph"
+
+// Стохастическая блочная модель: вершины разбиты на сообщества размеров sizes,
+// ребро между вершинами сообществ i и j появляется с вероятностью probs[i][j].
+// Возвращает граф и номер сообщества для каждой вершины.
+func StochasticBlockModel(sizes []int, probs [][]float64, opts Options) (*graph.Graph, []int) {
+	n := 0
+	for _, size := range sizes {
+		n += size
+	}
+	b := newBuilder(n, opts)
+
+	community := make([]int, 0, n)
+	for c, size := range sizes {
+		for i := 0; i < size; i++ {
+			community = append(community, c)
+		}
+	}
+
+	for u := 0; u < n; u++ {
+		for v := u + 1; v < n; v++ {
+			if b.rng.Float64() < probs[community[u]][community[v]] {
+				b.add(u, v)
+			}
+		}
+	}
+	return b.graph, community
+}
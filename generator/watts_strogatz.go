@@ -0,0 +1,51 @@
+package generator
+
+import "wintersc/graph"
+
+// Модель «тесного мира» Уоттса–Строгаца: кольцо, где каждая вершина
+// соединена с k ближайшими соседями (k/2 с каждой стороны), после чего
+// каждое ребро с вероятностью beta перенаправляется в случайную вершину.
+func WattsStrogatz(n, k int, beta float64, opts Options) *graph.Graph {
+	b := newBuilder(n, opts)
+	half := k / 2
+	if n < 3 || half < 1 {
+		return b.graph
+	}
+	half = min(half, (n-1)/2)
+
+	// Решаем заранее, какие рёбра решётки перенаправить, чтобы при
+	// перенаправлении не конфликтовать с ещё не добавленными рёбрами
+	type pair struct{ u, v int }
+	lattice := []pair{}
+	for u := 0; u < n; u++ {
+		for j := 1; j <= half; j++ {
+			lattice = append(lattice, pair{u, (u + j) % n})
+		}
+	}
+	rewire := make([]bool, len(lattice))
+	for i, e := range lattice {
+		if b.rng.Float64() < beta {
+			rewire[i] = true
+		} else {
+			b.add(e.u, e.v)
+		}
+	}
+
+	for i, e := range lattice {
+		if !rewire[i] {
+			continue
+		}
+		// Если вершина u уже соединена со всеми, оставляем исходное ребро
+		if b.graph.Degree(e.u) >= n-1 {
+			b.add(e.u, e.v)
+			continue
+		}
+		for {
+			v := b.rng.Intn(n)
+			if b.add(e.u, v) {
+				break
+			}
+		}
+	}
+	return b.graph
+}
@@ -1,7 +1,7 @@
 package graph
 
-func BFS(g *Graph, start int) []int {
-	if len(g.Adj) == 0 {
+func BFS(g View, start int) []int {
+	if g.NumVertices() == 0 {
 		return []int{}
 	}
 	visited := make(map[int]bool)
@@ -12,7 +12,7 @@ func BFS(g *Graph, start int) []int {
 	for !queue_slice.IsEmpty() {
 		u, _ := queue_slice.Dequeue()
 		order = append(order, u)
-		for _, neighbor := range g.Adj[u] {
+		for neighbor := range g.Neighbors(u) {
 			if !visited[neighbor] {
 				visited[neighbor] = true
 				queue_slice.Enqueue(neighbor)
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Потокобезопасная обёртка над Graph. Запись идёт под мьютексом,
+// а аналитика работает с неизменяемым CSR-снимком: он строится один раз
+// после каждой серии изменений и дальше читается без блокировок,
+// поэтому долгие обходы видят согласованное состояние и не мешают записи.
+type ConcurrentGraph struct {
+	mutex    sync.RWMutex
+	graph    *Graph
+	snapshot atomic.Pointer[CSR] // nil, если граф менялся после последнего снимка
+}
+
+func NewConcurrentGraph() *ConcurrentGraph {
+	return &ConcurrentGraph{graph: NewGraph()}
+}
+
+// Оборачивает существующий граф. Дальше его нельзя менять напрямую.
+func WrapGraph(g *Graph) *ConcurrentGraph {
+	return &ConcurrentGraph{graph: g}
+}
+
+func (c *ConcurrentGraph) AddVertex(u int) {
+	c.Update(func(g *Graph) { g.AddVertex(u) })
+}
+
+func (c *ConcurrentGraph) AddEdge(u, v, w int) {
+	c.Update(func(g *Graph) { g.AddEdge(u, v, w) })
+}
+
+// Применяет несколько изменений атомарно: читатели увидят либо все, либо ни одного
+func (c *ConcurrentGraph) Update(fn func(g *Graph)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fn(c.graph)
+	c.snapshot.Store(nil)
+}
+
+// Короткое чтение текущего состояния под блокировкой. g нельзя сохранять
+// или изменять после возврата из fn.
+func (c *ConcurrentGraph) Read(fn func(g *Graph)) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	fn(c.graph)
+}
+
+func (c *ConcurrentGraph) HasEdge(u, v int) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return HasEdge(c.graph, u, v)
+}
+
+// Неизменяемый снимок для BFS, ConnectedComponents, Dijkstra и т.д.
+// Пока граф не меняется, все вызовы возвращают один и тот же снимок.
+func (c *ConcurrentGraph) Snapshot() *CSR {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if s := c.snapshot.Load(); s != nil {
+		return s
+	}
+	// Писатели ждут снятия RLock, поэтому граф не меняется, пока строим снимок.
+	// Если несколько читателей строят его одновременно, остаётся первый.
+	c.snapshot.CompareAndSwap(nil, c.graph.Freeze())
+	return c.snapshot.Load()
+}
+
+// Изменяемая копия текущего состояния
+func (c *ConcurrentGraph) Clone() *Graph {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.graph.Clone()
+}
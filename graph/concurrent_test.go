@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"sync"
+	"testing"
+)
+
+// Писатели добавляют рёбра парами в одном Update, читатели параллельно
+// берут снимки и читают граф. Каждый читатель должен видеть либо обе
+// половины пары, либо ни одной. Запускать с -race.
+func TestConcurrentGraphReadersAndWriters(t *testing.T) {
+	const writers, readers, pairs = 4, 4, 50
+	c := NewConcurrentGraph()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < pairs; i++ {
+				u := w*pairs + i
+				c.Update(func(g *Graph) {
+					g.AddEdge(u, -u-1, 1)
+					g.AddEdge(-u-1, u+writers*pairs, 1)
+				})
+			}
+		}(w)
+	}
+
+	errs := make(chan string, readers)
+	done := make(chan struct{})
+	var readersWG sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		readersWG.Add(1)
+		go func() {
+			defer readersWG.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				s := c.Snapshot()
+				if s.NumEdges()%2 != 0 || s.NumVertices() != s.NumEdges()/2*3 {
+					errs <- "снимок увидел половину Update"
+					return
+				}
+				torn := false
+				c.Read(func(g *Graph) { torn = g.NumEdges()%2 != 0 })
+				if torn {
+					errs <- "Read увидел половину Update"
+					return
+				}
+				c.HasEdge(0, -1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readersWG.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	s := c.Snapshot()
+	if s.NumEdges() != writers*pairs*2 || s.NumVertices() != writers*pairs*3 {
+		t.Errorf("итог: %d вершин, %d рёбер", s.NumVertices(), s.NumEdges())
+	}
+	if s != c.Snapshot() {
+		t.Error("повторный Snapshot без изменений вернул другой снимок")
+	}
+	if clone := c.Clone(); clone.NumEdges() != s.NumEdges() {
+		t.Errorf("Clone: %d рёбер, ожидалось %d", clone.NumEdges(), s.NumEdges())
+	}
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"iter"
+	"sort"
+)
+
+// Неизменяемое представление графа в формате CSR (compressed sparse row).
+// Вершины перенумерованы в плотные индексы 0..n-1 по возрастанию ID.
+// Соседи вершины i лежат в Targets[Offsets[i]:Offsets[i+1]],
+// веса соответствующих рёбер — в том же диапазоне Weights.
+type CSR struct {
+	Offsets []int
+	Targets []int // Плотные индексы соседей
+	Weights []int
+	IDs     []int // Плотный индекс -> исходный ID вершины
+
+	index map[int]int // Исходный ID -> плотный индекс
+	edges int
+}
+
+// Снимок графа в формате CSR. Последующие изменения Graph на снимок не влияют.
+func (g *Graph) Freeze() *CSR {
+	ids := make([]int, 0, len(g.Adj))
+	for u := range g.Adj {
+		ids = append(ids, u)
+	}
+	sort.Ints(ids)
+
+	index := make(map[int]int, len(ids))
+	for i, u := range ids {
+		index[u] = i
+	}
+
+	offsets := make([]int, len(ids)+1)
+	for i, u := range ids {
+		offsets[i+1] = offsets[i] + len(g.Adj[u])
+	}
+
+	targets := make([]int, offsets[len(ids)])
+	weights := make([]int, offsets[len(ids)])
+	for i, u := range ids {
+		pos := offsets[i]
+		for _, v := range g.Adj[u] {
+			targets[pos] = index[v]
+			weights[pos] = g.edgeWeight(u, v)
+			pos++
+		}
+	}
+
+	return &CSR{
+		Offsets: offsets,
+		Targets: targets,
+		Weights: weights,
+		IDs:     ids,
+		index:   index,
+		edges:   g.NumEdges(),
+	}
+}
+
+// Плотный индекс вершины по её исходному ID
+func (c *CSR) Index(u int) (int, bool) {
+	i, exists := c.index[u]
+	return i, exists
+}
+
+func (c *CSR) Vertices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, u := range c.IDs {
+			if !yield(u) {
+				return
+			}
+		}
+	}
+}
+
+func (c *CSR) Neighbors(u int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		i, exists := c.index[u]
+		if !exists {
+			return
+		}
+		for pos := c.Offsets[i]; pos < c.Offsets[i+1]; pos++ {
+			if !yield(c.IDs[c.Targets[pos]], c.Weights[pos]) {
+				return
+			}
+		}
+	}
+}
+
+func (c *CSR) Degree(u int) int {
+	i, exists := c.index[u]
+	if !exists {
+		return 0
+	}
+	return c.Offsets[i+1] - c.Offsets[i]
+}
+
+func (c *CSR) HasVertex(u int) bool {
+	_, exists := c.index[u]
+	return exists
+}
+
+func (c *CSR) NumVertices() int {
+	return len(c.IDs)
+}
+
+func (c *CSR) NumEdges() int {
+	return c.edges
+}
@@ -1,7 +1,7 @@
 package graph
 
-func DFS(g *Graph, start int) []int {
-	if len(g.Adj) == 0 {
+func DFS(g View, start int) []int {
+	if g.NumVertices() == 0 {
 		return []int{}
 	}
 	visited := make(map[int]bool)
@@ -16,7 +16,7 @@ func DFS(g *Graph, start int) []int {
 			break
 		}
 		order = append(order, u)
-		for _, neighbor := range g.Adj[u] {
+		for neighbor := range g.Neighbors(u) {
 			if !visited[neighbor] {
 				visited[neighbor] = true
 				stack_slice.Push(neighbor)
@@ -1,52 +1,193 @@
 package graph
 
+import "iter"
+
 type Graph struct {
 	Adj  map[int][]int
 	Edge []Edge
+
+	weight  map[[2]int]int // Вес ребра (u, v) в обе стороны; у кратных рёбер — вес последнего
+	removed int            // Число удалённых рёбер, оставшихся в Edge как история
 }
 
+// Ребро с временем жизни. Created — момент появления (0 — существовало всегда),
+// Deleted — момент удаления (0 — ребро не удалено). Единицы времени выбирает
+// вызывающий код, например Unix-время в секундах.
 type Edge struct {
 	U, V, W int
+	Created int64
+	Deleted int64
+}
+
+// Существовало ли ребро в момент t
+func (e Edge) AliveAt(t int64) bool {
+	return e.Created <= t && (e.Deleted == 0 || e.Deleted > t)
 }
 
 func NewGraph() *Graph {
 	return &Graph{
-		Adj:  make(map[int][]int),
-		Edge: []Edge{},
+		Adj:    make(map[int][]int),
+		Edge:   []Edge{},
+		weight: make(map[[2]int]int),
 	}
 }
 
-func (g *Graph) AddEdge(u, v, w int) {
+// Добавляет изолированную вершину, если её ещё нет
+func (g *Graph) AddVertex(u int) {
 	if _, exists := g.Adj[u]; !exists {
 		g.Adj[u] = []int{}
 	}
-	if _, exists := g.Adj[v]; !exists {
-		g.Adj[v] = []int{}
+}
+
+func (g *Graph) AddEdge(u, v, w int) {
+	g.addEdge(Edge{U: u, V: v, W: w})
+}
+
+// Добавляет ребро, появившееся в момент t
+func (g *Graph) AddEdgeAt(u, v, w int, t int64) {
+	g.addEdge(Edge{U: u, V: v, W: w, Created: t})
+}
+
+// Добавляет ребро со всеми полями, включая время удаления.
+// Нужен для восстановления графа из сохранённой истории.
+func (g *Graph) InsertEdge(e Edge) {
+	g.addEdge(e)
+}
+
+// Добавляет ребро целиком, сохраняя все его поля.
+// Удалённые рёбра попадают только в историю Edge, но не в Adj.
+func (g *Graph) addEdge(e Edge) {
+	u, v, w := e.U, e.V, e.W
+	g.AddVertex(u)
+	g.AddVertex(v)
+	if e.Deleted != 0 {
+		g.Edge = append(g.Edge, e)
+		g.removed++
+		return
 	}
 	g.Adj[u] = append(g.Adj[u], v)
 	g.Adj[v] = append(g.Adj[v], u)
 
-	g.Edge = append(g.Edge, Edge{U: u, V: v, W: w})
+	g.Edge = append(g.Edge, e)
+
+	if g.weight == nil {
+		g.weight = make(map[[2]int]int)
+	}
+	// Для кратных рёбер действует вес последнего
+	g.weight[[2]int{u, v}] = w
+	g.weight[[2]int{v, u}] = w
 }
 
-func HasEdge(g *Graph, u, v int) bool {
-	if neighbors, exists := g.Adj[u]; exists {
-		for _, neighbor := range neighbors {
-			if neighbor == v {
-				return true
-			}
+// Помечает ребро (u, v) удалённым в момент t (t > 0). Ребро остаётся в Edge
+// как история, но пропадает из Adj. Возвращает false и ничего не меняет,
+// если ребра нет, t <= 0 или t раньше появления ребра: с Deleted = 0 ребро
+// считалось бы живым и воскресло бы в Clone и при восстановлении из журнала.
+func (g *Graph) RemoveEdgeAt(u, v int, t int64) bool {
+	i := g.findEdge(u, v)
+	if i < 0 || t <= 0 || t < g.Edge[i].Created {
+		return false
+	}
+	g.Edge[i].Deleted = t
+	g.removed++
+	g.unlink(u, v)
+	return true
+}
+
+// Удаляет ребро (u, v) полностью, без сохранения истории.
+// Возвращает false, если ребра нет.
+func (g *Graph) RemoveEdge(u, v int) bool {
+	i := g.findEdge(u, v)
+	if i < 0 {
+		return false
+	}
+	g.Edge = append(g.Edge[:i], g.Edge[i+1:]...)
+	g.unlink(u, v)
+	return true
+}
+
+// Индекс первого неудалённого ребра (u, v) в Edge или -1
+func (g *Graph) findEdge(u, v int) int {
+	for i, edge := range g.Edge {
+		if edge.Deleted == 0 && ((edge.U == u && edge.V == v) || (edge.U == v && edge.V == u)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Индекс последнего неудалённого ребра (u, v) в Edge или -1
+func (g *Graph) findLastEdge(u, v int) int {
+	for i := len(g.Edge) - 1; i >= 0; i-- {
+		edge := g.Edge[i]
+		if edge.Deleted == 0 && ((edge.U == u && edge.V == v) || (edge.U == v && edge.V == u)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Убирает одно ребро (u, v) из списков смежности и обновляет индекс весов.
+// Петля (u, u) записана в Adj[u] дважды, как и добавлялась в addEdge.
+func (g *Graph) unlink(u, v int) {
+	g.Adj[u] = removeOnce(g.Adj[u], v)
+	g.Adj[v] = removeOnce(g.Adj[v], u)
+	delete(g.weight, [2]int{u, v})
+	delete(g.weight, [2]int{v, u})
+	// Если осталось кратное ребро, индексируем вес последнего из оставшихся
+	if i := g.findLastEdge(u, v); i >= 0 && g.weight != nil {
+		g.weight[[2]int{u, v}] = g.Edge[i].W
+		g.weight[[2]int{v, u}] = g.Edge[i].W
+	}
+}
+
+func removeOnce(list []int, x int) []int {
+	for i, y := range list {
+		if y == x {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// Глубокая копия графа вместе с историей рёбер
+func (g *Graph) Clone() *Graph {
+	result := NewGraph()
+	for u := range g.Adj {
+		result.AddVertex(u)
+	}
+	for _, edge := range g.Edge {
+		result.addEdge(edge)
+	}
+	return result
+}
+
+// Вес ребра (u, v). Если индекс не заполнен (граф собран вручную), ищем в списке рёбер.
+func (g *Graph) edgeWeight(u, v int) int {
+	if w, exists := g.weight[[2]int{u, v}]; exists {
+		return w
+	}
+	if i := g.findLastEdge(u, v); i >= 0 {
+		return g.Edge[i].W
+	}
+	return 0
+}
+
+func HasEdge(g View, u, v int) bool {
+	for neighbor := range g.Neighbors(u) {
+		if neighbor == v {
+			return true
 		}
 	}
 	return false
 }
 
-func ConnectedComponents(g *Graph) (count int, comp map[int]int) {
+func ConnectedComponents(g View) (count int, comp map[int]int) {
 	visited := make(map[int]bool) // Для отслеживания посещённых узлов
 	comp = make(map[int]int)      // Для хранения компонент связности
 	count = 0                     // Счётчик компонент связности
 
 	// Перебираем все узлы графа
-	for key := range g.Adj {
+	for key := range g.Vertices() {
 		if !visited[key] {
 			count++ // Новая компонента связности
 			// Получаем все узлы компоненты с помощью DFS
@@ -63,21 +204,13 @@ func ConnectedComponents(g *Graph) (count int, comp map[int]int) {
 }
 
 func (g *Graph) GetAllEdges() []Edge {
-	// Создаём карту для быстрого поиска веса ребра
-	weights := make(map[[2]int]int)
-	for _, edge := range g.Edge {
-		weights[[2]int{edge.U, edge.V}] = edge.W
-		weights[[2]int{edge.V, edge.U}] = edge.W // Для неориентированного графа
-	}
-
 	var edges []Edge
 	// Перебираем каждую вершину
 	for u, neighbors := range g.Adj {
 		for _, v := range neighbors {
 			// Избегаем дублирования рёбер, добавляя только (u, v) где u < v
 			if u < v {
-				weight := weights[[2]int{u, v}]
-				edges = append(edges, Edge{U: u, V: v, W: weight})
+				edges = append(edges, Edge{U: u, V: v, W: g.edgeWeight(u, v)})
 			}
 		}
 	}
@@ -88,12 +221,46 @@ func (g *Graph) GetNeighbors(u int) []struct{ V, W int } {
 	var neighbors []struct{ V, W int }
 
 	for _, v := range g.Adj[u] {
-		for _, edge := range g.Edge {
-			if (edge.U == u && edge.V == v) || (edge.U == v && edge.V == u) {
-				neighbors = append(neighbors, struct{ V, W int }{V: v, W: edge.W})
-				break
+		neighbors = append(neighbors, struct{ V, W int }{V: v, W: g.edgeWeight(u, v)})
+	}
+	return neighbors
+}
+
+// Реализация View
+
+func (g *Graph) Vertices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for u := range g.Adj {
+			if !yield(u) {
+				return
 			}
 		}
 	}
-	return neighbors
+}
+
+func (g *Graph) Neighbors(u int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for _, v := range g.Adj[u] {
+			if !yield(v, g.edgeWeight(u, v)) {
+				return
+			}
+		}
+	}
+}
+
+func (g *Graph) Degree(u int) int {
+	return len(g.Adj[u])
+}
+
+func (g *Graph) HasVertex(u int) bool {
+	_, exists := g.Adj[u]
+	return exists
+}
+
+func (g *Graph) NumVertices() int {
+	return len(g.Adj)
+}
+
+func (g *Graph) NumEdges() int {
+	return len(g.Edge) - g.removed
 }
@@ -0,0 +1,51 @@
+package graph
+
+import "testing"
+
+func TestSelfLoopAddRemove(t *testing.T) {
+	g := NewGraph()
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(1, 1, 5)
+	if g.Degree(1) != 3 || g.NumEdges() != 2 {
+		t.Fatalf("с петлёй: степень %d, рёбер %d, ожидалось 3 и 2", g.Degree(1), g.NumEdges())
+	}
+	if !g.RemoveEdge(1, 1) {
+		t.Fatal("RemoveEdge(1, 1) не нашёл петлю")
+	}
+	if g.Degree(1) != 1 || g.NumEdges() != 1 || HasEdge(g, 1, 1) {
+		t.Errorf("после удаления петли: степень %d, рёбер %d, Adj[1] = %v", g.Degree(1), g.NumEdges(), g.Adj[1])
+	}
+
+	// Удаление с историей тоже убирает обе записи петли
+	g.AddEdge(1, 1, 5)
+	if !g.RemoveEdgeAt(1, 1, 10) {
+		t.Fatal("RemoveEdgeAt(1, 1) не нашёл петлю")
+	}
+	if g.Degree(1) != 1 || g.NumEdges() != 1 {
+		t.Errorf("после RemoveEdgeAt: степень %d, рёбер %d, Adj[1] = %v", g.Degree(1), g.NumEdges(), g.Adj[1])
+	}
+	if c := g.Clone(); c.Degree(1) != 1 || c.NumEdges() != 1 {
+		t.Errorf("Clone: степень %d, рёбер %d", c.Degree(1), c.NumEdges())
+	}
+}
+
+func TestRemoveEdgeAtValidatesTime(t *testing.T) {
+	g := NewGraph()
+	g.AddEdgeAt(1, 2, 1, 100)
+	for _, at := range []int64{0, -5, 99} {
+		if g.RemoveEdgeAt(1, 2, at) {
+			t.Errorf("RemoveEdgeAt в момент %d удалил ребро, созданное в 100", at)
+		}
+	}
+	if !HasEdge(g, 1, 2) || g.NumEdges() != 1 {
+		t.Fatal("ребро пропало после отклонённых удалений")
+	}
+
+	if !g.RemoveEdgeAt(1, 2, 100) {
+		t.Fatal("RemoveEdgeAt в момент появления не удалил ребро")
+	}
+	// Удалённое ребро не воскресает в копии
+	if c := g.Clone(); HasEdge(c, 1, 2) || c.NumEdges() != 0 || len(c.Edge) != 1 {
+		t.Errorf("Clone: рёбер %d, история %v", c.NumEdges(), c.Edge)
+	}
+}
@@ -8,10 +8,11 @@ func BoruvkaMST(n int, edges []Edge) (mst []Edge, totalWeight int) {
 	numComponents := n
 
 	for numComponents > 1 {
+		merged := false
 		minEdges := make([]Edge, n)
 
 		for i := range minEdges {
-			minEdges[i] = Edge{-1, -1, int(^uint(0) >> 1)}
+			minEdges[i] = Edge{U: -1, V: -1, W: int(^uint(0) >> 1)}
 		}
 
 		for _, edge := range edges {
@@ -42,8 +43,37 @@ func BoruvkaMST(n int, edges []Edge) (mst []Edge, totalWeight int) {
 				mst = append(mst, edge)
 				totalWeight += w
 				numComponents--
+				merged = true
 			}
 		}
+
+		// Граф несвязный: остовный лес построен
+		if !merged {
+			break
+		}
+	}
+	return mst, totalWeight
+}
+
+// Минимальный остовный лес для любого представления графа.
+// Вершины перенумеровываются в 0..n-1, в результате остаются исходные ID.
+func MST(g View) (mst []Edge, totalWeight int) {
+	ids := []int{}
+	index := make(map[int]int)
+	for u := range g.Vertices() {
+		index[u] = len(ids)
+		ids = append(ids, u)
+	}
+
+	edges := []Edge{}
+	for edge := range Edges(g) {
+		edges = append(edges, Edge{U: index[edge.U], V: index[edge.V], W: edge.W})
+	}
+
+	mst, totalWeight = BoruvkaMST(len(ids), edges)
+	for i := range mst {
+		mst[i].U = ids[mst[i].U]
+		mst[i].V = ids[mst[i].V]
 	}
 	return mst, totalWeight
 }
@@ -25,7 +25,7 @@ func (q *PriorityQueue) HeapfiUp() {
 	for i > 0 {
 		parentIndex := (i - 1) / 2
 
-		if q.Data[parentIndex].Vertex > q.Data[i].Vertex {
+		if q.Data[parentIndex].Dist > q.Data[i].Dist {
 			q.Data[parentIndex], q.Data[i] = q.Data[i], q.Data[parentIndex]
 			i = parentIndex
 		} else {
@@ -56,11 +56,11 @@ func (q *PriorityQueue) HeapfiDown() {
 		rightIndex := 2*i + 2
 		smallestIndex := i
 
-		if leftIndex < len(q.Data) && q.Data[leftIndex].Vertex < q.Data[smallestIndex].Vertex {
+		if leftIndex < len(q.Data) && q.Data[leftIndex].Dist < q.Data[smallestIndex].Dist {
 			smallestIndex = leftIndex
 		}
 
-		if rightIndex < len(q.Data) && q.Data[rightIndex].Vertex < q.Data[smallestIndex].Vertex {
+		if rightIndex < len(q.Data) && q.Data[rightIndex].Dist < q.Data[smallestIndex].Dist {
 			smallestIndex = rightIndex
 		}
 
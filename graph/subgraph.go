@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"iter"
+)
+
+// Ленивое представление подграфа поверх Graph. Данные не копируются:
+// фильтры применяются при каждом обращении, поэтому изменения базового
+// графа сразу видны через представление.
+type Subgraph struct {
+	base       *Graph
+	keepVertex func(u int) bool       // nil — все вершины
+	keepEdge   func(u, v, w int) bool // nil — все рёбра
+}
+
+// Подграф, порождённый множеством вершин: вершины и все рёбра между ними
+func Induced(g *Graph, vertices []int) *Subgraph {
+	set := make(map[int]bool, len(vertices))
+	for _, u := range vertices {
+		if g.HasVertex(u) {
+			set[u] = true
+		}
+	}
+	return &Subgraph{
+		base:       g,
+		keepVertex: func(u int) bool { return set[u] },
+	}
+}
+
+// Все вершины графа и только рёбра, удовлетворяющие условию
+func FilterEdges(g *Graph, keep func(u, v, w int) bool) *Subgraph {
+	return &Subgraph{base: g, keepEdge: keep}
+}
+
+// Эго-сеть: вершина center, все вершины на расстоянии до k переходов
+// и все рёбра между ними
+func EgoNetwork(g *Graph, center, k int) *Subgraph {
+	vertices := []int{}
+	opts := TraversalOptions{MaxDepth: k}
+	if k <= 0 {
+		// Нулевой радиус — только сама вершина
+		opts = TraversalOptions{MaxVisits: 1}
+	}
+	for visit := range BFSSeq(context.Background(), g, center, opts) {
+		vertices = append(vertices, visit.Vertex)
+	}
+	return Induced(g, vertices)
+}
+
+func (s *Subgraph) vertexOK(u int) bool {
+	return s.base.HasVertex(u) && (s.keepVertex == nil || s.keepVertex(u))
+}
+
+func (s *Subgraph) edgeOK(u, v, w int) bool {
+	if !s.vertexOK(u) || !s.vertexOK(v) {
+		return false
+	}
+	return s.keepEdge == nil || s.keepEdge(u, v, w)
+}
+
+func (s *Subgraph) Vertices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for u := range s.base.Vertices() {
+			if s.vertexOK(u) && !yield(u) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Subgraph) Neighbors(u int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		if !s.vertexOK(u) {
+			return
+		}
+		for v, w := range s.base.Neighbors(u) {
+			if s.edgeOK(u, v, w) && !yield(v, w) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Subgraph) Degree(u int) int {
+	degree := 0
+	for range s.Neighbors(u) {
+		degree++
+	}
+	return degree
+}
+
+func (s *Subgraph) HasVertex(u int) bool {
+	return s.vertexOK(u)
+}
+
+func (s *Subgraph) NumVertices() int {
+	count := 0
+	for range s.Vertices() {
+		count++
+	}
+	return count
+}
+
+func (s *Subgraph) NumEdges() int {
+	count := 0
+	for _, edge := range s.base.Edge {
+		if edge.Deleted == 0 && s.edgeOK(edge.U, edge.V, edge.W) {
+			count++
+		}
+	}
+	return count
+}
+
+// Копирует подграф в новый Graph. Живые рёбра переносятся со всеми полями,
+// удалённые, как и в остальных методах представления, не видны.
+// Изолированные вершины подграфа сохраняются.
+func (s *Subgraph) Materialize() *Graph {
+	result := NewGraph()
+	for u := range s.Vertices() {
+		result.AddVertex(u)
+	}
+	for _, edge := range s.base.Edge {
+		if edge.Deleted == 0 && s.edgeOK(edge.U, edge.V, edge.W) {
+			result.addEdge(edge)
+		}
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Квадрат 1 — 2 — 3 — 4 — 1 с диагональю 1 — 3 и хвостом 4 — 5 — 6
+func subgraphBase() *Graph {
+	g := NewGraph()
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(2, 3, 2)
+	g.AddEdge(3, 4, 3)
+	g.AddEdge(4, 1, 4)
+	g.AddEdge(1, 3, 5)
+	g.AddEdge(4, 5, 6)
+	g.AddEdge(5, 6, 7)
+	return g
+}
+
+func sortedVertices(g View) []int {
+	return slices.Sorted(g.Vertices())
+}
+
+func neighborSet(g View, u int) map[int]int {
+	result := map[int]int{}
+	for v, w := range g.Neighbors(u) {
+		result[v] = w
+	}
+	return result
+}
+
+func TestInduced(t *testing.T) {
+	g := subgraphBase()
+	// Вершины 42 нет в графе, она не попадает в подграф
+	s := Induced(g, []int{1, 2, 3, 42})
+	if got := sortedVertices(s); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("вершины %v, ожидались [1 2 3]", got)
+	}
+	if s.NumVertices() != 3 || s.NumEdges() != 3 {
+		t.Errorf("NumVertices %d, NumEdges %d, ожидалось 3 и 3", s.NumVertices(), s.NumEdges())
+	}
+	if got := neighborSet(s, 1); !reflect.DeepEqual(got, map[int]int{2: 1, 3: 5}) {
+		t.Errorf("соседи 1: %v", got)
+	}
+	if s.Degree(3) != 2 || s.Degree(4) != 0 {
+		t.Errorf("степени 3 и 4: %d и %d, ожидалось 2 и 0", s.Degree(3), s.Degree(4))
+	}
+	if s.HasVertex(4) || HasEdge(s, 3, 4) {
+		t.Error("вершина 4 и ребро 3 — 4 видны вне подграфа")
+	}
+}
+
+func TestFilterEdges(t *testing.T) {
+	s := FilterEdges(subgraphBase(), func(u, v, w int) bool { return w%2 == 1 })
+	// Вершины остаются все, даже потерявшие рёбра
+	if s.NumVertices() != 6 {
+		t.Errorf("NumVertices %d, ожидалось 6", s.NumVertices())
+	}
+	if s.NumEdges() != 4 {
+		t.Errorf("NumEdges %d, ожидалось 4", s.NumEdges())
+	}
+	if got := neighborSet(s, 4); !reflect.DeepEqual(got, map[int]int{3: 3}) {
+		t.Errorf("соседи 4: %v", got)
+	}
+	if got := neighborSet(s, 6); !reflect.DeepEqual(got, map[int]int{5: 7}) {
+		t.Errorf("соседи 6: %v", got)
+	}
+}
+
+func TestEgoNetwork(t *testing.T) {
+	g := subgraphBase()
+	tests := []struct {
+		k     int
+		want  []int
+		edges int
+	}{
+		{0, []int{5}, 0},
+		{1, []int{4, 5, 6}, 2},
+		{2, []int{1, 3, 4, 5, 6}, 5},
+	}
+	for _, tt := range tests {
+		s := EgoNetwork(g, 5, tt.k)
+		if got := sortedVertices(s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("k = %d: вершины %v, ожидались %v", tt.k, got, tt.want)
+		}
+		if s.NumEdges() != tt.edges {
+			t.Errorf("k = %d: NumEdges %d, ожидалось %d", tt.k, s.NumEdges(), tt.edges)
+		}
+	}
+}
+
+// Представление ленивое: изменения базового графа видны сразу
+func TestSubgraphIsLive(t *testing.T) {
+	g := subgraphBase()
+	s := Induced(g, []int{1, 2, 3})
+	g.AddEdge(2, 3, 8)
+	g.RemoveEdge(1, 2)
+	if s.NumEdges() != 3 {
+		t.Errorf("NumEdges %d, ожидалось 3", s.NumEdges())
+	}
+	if HasEdge(s, 1, 2) {
+		t.Error("удалённое ребро 1 — 2 видно в подграфе")
+	}
+	if got := s.Degree(2); got != 2 {
+		t.Errorf("степень 2: %d, ожидалось 2 (кратное ребро 2 — 3)", got)
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	g := subgraphBase()
+	g.AddVertex(7)
+	// Удалённое ребро остаётся в истории базового графа, но не в копии
+	g.AddEdgeAt(2, 7, 1, 10)
+	g.RemoveEdgeAt(2, 7, 20)
+	m := Induced(g, []int{1, 2, 3, 7}).Materialize()
+	if got := sortedVertices(m); !reflect.DeepEqual(got, []int{1, 2, 3, 7}) {
+		t.Errorf("вершины %v, ожидались [1 2 3 7]", got)
+	}
+	if m.NumEdges() != 3 || len(m.Edge) != 3 || m.edgeWeight(1, 3) != 5 {
+		t.Errorf("NumEdges %d, история %v, вес 1 — 3 %d", m.NumEdges(), m.Edge, m.edgeWeight(1, 3))
+	}
+	// Копия не зависит от исходного графа
+	g.AddEdge(1, 7, 9)
+	if HasEdge(m, 1, 7) {
+		t.Error("ребро исходного графа появилось в копии")
+	}
+}
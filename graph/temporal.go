@@ -0,0 +1,80 @@
+package graph
+
+// Состояние графа на момент t: только рёбра, существовавшие в этот момент,
+// и их концы
+func (g *Graph) AsOf(t int64) *Graph {
+	result := NewGraph()
+	for _, edge := range g.Edge {
+		if edge.AliveAt(t) {
+			edge.Deleted = 0 // На момент t ребро ещё не удалено
+			result.addEdge(edge)
+		}
+	}
+	return result
+}
+
+// Рёбра, появившиеся и исчезнувшие между моментами from и to.
+// Рёбра, созданные и удалённые внутри интервала, не попадают ни в один список.
+func (g *Graph) Diff(from, to int64) (added, removed []Edge) {
+	for _, edge := range g.Edge {
+		before, after := edge.AliveAt(from), edge.AliveAt(to)
+		if !before && after {
+			added = append(added, edge)
+		}
+		if before && !after {
+			removed = append(removed, edge)
+		}
+	}
+	return added, removed
+}
+
+// Обход по путям, согласованным во времени: из вершины, достигнутой в момент a,
+// можно пройти по ребру в любой момент из [max(a, Created), Deleted) внутри
+// окна [from, to]. Возвращает самое раннее время прибытия в каждую
+// достижимую вершину и предка на этом пути (-1 для start).
+func TemporalBFS(g *Graph, start int, from, to int64) (arrival map[int]int64, prev map[int]int) {
+	arrival = make(map[int]int64)
+	prev = make(map[int]int)
+	if _, exists := g.Adj[start]; !exists || from > to {
+		return arrival, prev
+	}
+
+	// Рёбра каждой вершины, включая удалённые: они существовали в прошлом
+	incident := make(map[int][]int)
+	for i, edge := range g.Edge {
+		incident[edge.U] = append(incident[edge.U], i)
+		if edge.V != edge.U {
+			incident[edge.V] = append(incident[edge.V], i)
+		}
+	}
+
+	arrival[start] = from
+	prev[start] = -1
+	queue_slice := &Queue{}
+	queue_slice.Enqueue(start)
+	for !queue_slice.IsEmpty() {
+		u, _ := queue_slice.Dequeue()
+		for _, i := range incident[u] {
+			edge := g.Edge[i]
+			v := edge.V
+			if v == u {
+				v = edge.U
+			}
+
+			// Самый ранний момент, когда ребро можно пройти
+			at := max(arrival[u], edge.Created)
+			if at > to || (edge.Deleted != 0 && at >= edge.Deleted) {
+				continue
+			}
+
+			// Время прибытия улучшилось: вершину нужно обработать заново
+			if best, seen := arrival[v]; !seen || at < best {
+				arrival[v] = at
+				prev[v] = u
+				queue_slice.Enqueue(v)
+			}
+		}
+	}
+
+	return arrival, prev
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"iter"
+)
+
+// Посещение вершины при обходе
+type Visit struct {
+	Vertex    int
+	Depth     int
+	Parent    int  // Задан, только если HasParent
+	HasParent bool // false для стартовой вершины
+}
+
+// Ограничения обхода. Нулевые значения означают "без ограничений".
+type TraversalOptions struct {
+	MaxDepth  int // Максимальная глубина (0 — без ограничения)
+	MaxVisits int // Бюджет посещений (0 — без ограничения)
+}
+
+// Ленивый обход в ширину. Останавливается при отмене контекста,
+// исчерпании бюджета или если потребитель прервал цикл.
+func BFSSeq(ctx context.Context, g View, start int, opts TraversalOptions) iter.Seq[Visit] {
+	return func(yield func(Visit) bool) {
+		if !g.HasVertex(start) {
+			return
+		}
+		visited := map[int]bool{start: true}
+		queue := []Visit{{Vertex: start, Depth: 0}}
+		visits := 0
+		for len(queue) > 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			cur := queue[0]
+			queue = queue[1:]
+			if !yield(cur) {
+				return
+			}
+			visits++
+			if opts.MaxVisits > 0 && visits >= opts.MaxVisits {
+				return
+			}
+			if opts.MaxDepth > 0 && cur.Depth >= opts.MaxDepth {
+				continue
+			}
+			for neighbor := range g.Neighbors(cur.Vertex) {
+				if !visited[neighbor] {
+					visited[neighbor] = true
+					queue = append(queue, Visit{Vertex: neighbor, Depth: cur.Depth + 1, Parent: cur.Vertex, HasParent: true})
+				}
+			}
+		}
+	}
+}
+
+// Ленивый обход в глубину с теми же ограничениями, что и BFSSeq.
+// Вершина помечается, когда снимается со стека, поэтому порядок посещений —
+// настоящий порядок DFS (соседи — в порядке Neighbors), а Parent и Depth
+// описывают дерево DFS. Вершину, которая лежит в стеке несколько раз,
+// посещает первое снятие. С MaxDepth, как в любом DFS с ограничением
+// глубины, вершина, впервые достигнутая слишком глубоким путём, может
+// не попасть в обход, даже если есть путь короче.
+func DFSSeq(ctx context.Context, g View, start int, opts TraversalOptions) iter.Seq[Visit] {
+	return func(yield func(Visit) bool) {
+		if !g.HasVertex(start) {
+			return
+		}
+		visited := make(map[int]bool)
+		stack := []Visit{{Vertex: start, Depth: 0}}
+		visits := 0
+		var next []Visit
+		for len(stack) > 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[cur.Vertex] {
+				continue
+			}
+			visited[cur.Vertex] = true
+			if !yield(cur) {
+				return
+			}
+			visits++
+			if opts.MaxVisits > 0 && visits >= opts.MaxVisits {
+				return
+			}
+			if opts.MaxDepth > 0 && cur.Depth >= opts.MaxDepth {
+				continue
+			}
+			// Кладём соседей в обратном порядке, чтобы первым снять первого
+			next = next[:0]
+			for neighbor := range g.Neighbors(cur.Vertex) {
+				if !visited[neighbor] {
+					next = append(next, Visit{Vertex: neighbor, Depth: cur.Depth + 1, Parent: cur.Vertex, HasParent: true})
+				}
+			}
+			for i := len(next) - 1; i >= 0; i-- {
+				stack = append(stack, next[i])
+			}
+		}
+	}
+}
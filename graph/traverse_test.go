@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"testing"
+)
+
+// Путь -1 — 0 — 1 — 2 — 3 и ветка 0 — 4: вершина -1 ничем не отличается от других
+func traversalGraph() *Graph {
+	g := NewGraph()
+	g.AddEdge(-1, 0, 1)
+	g.AddEdge(0, 1, 1)
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(2, 3, 1)
+	g.AddEdge(0, 4, 1)
+	return g
+}
+
+func collect(seq iter.Seq[Visit]) []Visit {
+	var visits []Visit
+	for v := range seq {
+		visits = append(visits, v)
+	}
+	return visits
+}
+
+func TestBFSSeqDepthAndParent(t *testing.T) {
+	visits := collect(BFSSeq(context.Background(), traversalGraph(), -1, TraversalOptions{}))
+	want := []Visit{
+		{Vertex: -1, Depth: 0},
+		{Vertex: 0, Depth: 1, Parent: -1, HasParent: true},
+		{Vertex: 1, Depth: 2, Parent: 0, HasParent: true},
+		{Vertex: 4, Depth: 2, Parent: 0, HasParent: true},
+		{Vertex: 2, Depth: 3, Parent: 1, HasParent: true},
+		{Vertex: 3, Depth: 4, Parent: 2, HasParent: true},
+	}
+	if !reflect.DeepEqual(visits, want) {
+		t.Errorf("BFSSeq:\n%v\nожидалось\n%v", visits, want)
+	}
+}
+
+func TestDFSSeqOrder(t *testing.T) {
+	visits := collect(DFSSeq(context.Background(), traversalGraph(), 0, TraversalOptions{}))
+	want := []Visit{
+		{Vertex: 0, Depth: 0},
+		{Vertex: -1, Depth: 1, Parent: 0, HasParent: true},
+		{Vertex: 1, Depth: 1, Parent: 0, HasParent: true},
+		{Vertex: 2, Depth: 2, Parent: 1, HasParent: true},
+		{Vertex: 3, Depth: 3, Parent: 2, HasParent: true},
+		{Vertex: 4, Depth: 1, Parent: 0, HasParent: true},
+	}
+	if !reflect.DeepEqual(visits, want) {
+		t.Errorf("DFSSeq:\n%v\nожидалось\n%v", visits, want)
+	}
+
+	// В треугольнике 1 — 2 — 3 вершина 3 достигается через 2, как в дереве DFS
+	g := NewGraph()
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(1, 3, 1)
+	g.AddEdge(2, 3, 1)
+	visits = collect(DFSSeq(context.Background(), g, 1, TraversalOptions{}))
+	if last := visits[len(visits)-1]; last.Vertex != 3 || last.Parent != 2 || last.Depth != 2 {
+		t.Errorf("последнее посещение %+v, ожидалась вершина 3 с родителем 2 на глубине 2", last)
+	}
+}
+
+func TestTraversalLimits(t *testing.T) {
+	traversals := map[string]func(context.Context, View, int, TraversalOptions) iter.Seq[Visit]{
+		"BFSSeq": BFSSeq,
+		"DFSSeq": DFSSeq,
+	}
+	g := traversalGraph()
+	for name, traverse := range traversals {
+		t.Run(name, func(t *testing.T) {
+			for _, v := range collect(traverse(context.Background(), g, -1, TraversalOptions{MaxDepth: 2})) {
+				if v.Depth > 2 {
+					t.Errorf("MaxDepth 2: посещена %+v", v)
+				}
+			}
+			if n := len(collect(traverse(context.Background(), g, -1, TraversalOptions{MaxDepth: 2}))); n != 4 {
+				t.Errorf("MaxDepth 2: посещено %d вершин, ожидалось 4", n)
+			}
+			if n := len(collect(traverse(context.Background(), g, -1, TraversalOptions{MaxVisits: 3}))); n != 3 {
+				t.Errorf("MaxVisits 3: посещено %d вершин", n)
+			}
+
+			// Потребитель прерывает цикл
+			n := 0
+			for range traverse(context.Background(), g, -1, TraversalOptions{}) {
+				n++
+				if n == 2 {
+					break
+				}
+			}
+			if n != 2 {
+				t.Errorf("после break посещено %d вершин", n)
+			}
+
+			// Отмена контекста посреди обхода
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			n = 0
+			for range traverse(ctx, g, -1, TraversalOptions{}) {
+				n++
+				cancel()
+			}
+			if n != 1 {
+				t.Errorf("после отмены контекста посещено %d вершин, ожидалась 1", n)
+			}
+
+			if n := len(collect(traverse(context.Background(), g, 42, TraversalOptions{}))); n != 0 {
+				t.Errorf("обход из несуществующей вершины посетил %d вершин", n)
+			}
+		})
+	}
+}
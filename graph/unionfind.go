@@ -39,7 +39,7 @@ func (ds *DisjointSet) Union(x, y int) bool {
 	} else if ds.Rank[rootX] < ds.Rank[rootY] {
 		ds.Parent[rootX] = rootY
 	} else {
-		ds.Rank[rootY] = rootX
+		ds.Parent[rootY] = rootX
 		ds.Rank[rootX]++
 	}
 	return true
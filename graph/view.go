@@ -0,0 +1,38 @@
+package graph
+
+import "iter"
+
+// Граф только для чтения. Его реализуют Graph и CSR, поэтому
+// алгоритмы, принимающие View, работают с любым представлением.
+type View interface {
+	Vertices() iter.Seq[int]
+	Neighbors(u int) iter.Seq2[int, int] // Сосед и вес ребра
+	Degree(u int) int
+	HasVertex(u int) bool
+	NumVertices() int
+	NumEdges() int
+}
+
+// Все рёбра представления, каждое неориентированное ребро ровно один раз,
+// включая петли. Кратное ребро перечисляется столько раз, сколько у него
+// копий, поэтому число рёбер совпадает с NumEdges; вес у всех копий один —
+// тот, что возвращает Neighbors.
+func Edges(g View) iter.Seq[Edge] {
+	return func(yield func(Edge) bool) {
+		for u := range g.Vertices() {
+			// Петля (u, u) встречается среди соседей u дважды
+			loops := 0
+			for v, w := range g.Neighbors(u) {
+				if v == u {
+					loops++
+					if loops%2 == 0 {
+						continue
+					}
+				}
+				if u <= v && !yield(Edge{U: u, V: v, W: w}) {
+					return
+				}
+			}
+		}
+	}
+}
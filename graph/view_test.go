@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"sort"
+	"testing"
+)
+
+// Edges перечисляет петли один раз и кратные рёбра по числу копий
+func TestEdgesMatchesNumEdges(t *testing.T) {
+	g := NewGraph()
+	g.AddEdge(1, 2, 3)
+	g.AddEdge(1, 2, 4)
+	g.AddEdge(2, 3, 1)
+	g.AddEdge(3, 3, 5)
+	g.AddEdge(3, 3, 6)
+	g.AddEdge(4, 4, 7)
+
+	var got []Edge
+	for edge := range Edges(g) {
+		got = append(got, edge)
+	}
+	if len(got) != g.NumEdges() {
+		t.Fatalf("Edges дал %d рёбер, NumEdges = %d: %v", len(got), g.NumEdges(), got)
+	}
+	sort.Slice(got, func(i, j int) bool {
+		return got[i].U < got[j].U || got[i].U == got[j].U && got[i].V < got[j].V
+	})
+	want := []Edge{{U: 1, V: 2, W: 4}, {U: 1, V: 2, W: 4}, {U: 2, V: 3, W: 1}, {U: 3, V: 3, W: 6}, {U: 3, V: 3, W: 6}, {U: 4, V: 4, W: 7}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ребро %d: %+v, ожидалось %+v", i, got[i], want[i])
+		}
+	}
+}
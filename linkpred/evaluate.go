@@ -0,0 +1,145 @@
+package linkpred
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"wintersc/graph"
+)
+
+// Качество предсказания на тестовой выборке
+type Result struct {
+	Name         string  `json:"name"`
+	AUC          float64 `json:"auc"`
+	PrecisionAtK float64 `json:"precision_at_k"`
+	RecallAtK    float64 `json:"recall_at_k"`
+	MAP          float64 `json:"map"`
+	K            int     `json:"k"`
+}
+
+type scored struct {
+	u        int
+	score    float64
+	positive bool
+}
+
+// Оценивает scorer на обучающем графе: положительные примеры — тестовые
+// рёбра, отрицательные — negatives. Precision@k и recall@k считаются по общему
+// рейтингу всех пар, MAP — по рейтингам пар, сгруппированных по вершине U.
+func Evaluate(train graph.View, test []graph.Edge, negatives []Pair, scorer Scorer, k int) Result {
+	pairs := make([]scored, 0, len(test)+len(negatives))
+	for _, edge := range test {
+		pairs = append(pairs, scored{edge.U, scorer.Score(train, edge.U, edge.V), true})
+	}
+	for _, p := range negatives {
+		pairs = append(pairs, scored{p.U, scorer.Score(train, p.U, p.V), false})
+	}
+
+	// Устойчивая сортировка по убыванию: при равных оценках отрицательные примеры
+	// идут первыми, чтобы эвристика не выигрывала на ничьих
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].score != pairs[j].score {
+			return pairs[i].score > pairs[j].score
+		}
+		return !pairs[i].positive && pairs[j].positive
+	})
+
+	result := Result{K: k, AUC: auc(pairs)}
+	if len(test) > 0 && k > 0 {
+		// Если пар меньше k, точность считается по всем парам
+		top := min(k, len(pairs))
+		hits := 0
+		for _, p := range pairs[:top] {
+			if p.positive {
+				hits++
+			}
+		}
+		result.PrecisionAtK = float64(hits) / float64(top)
+		result.RecallAtK = float64(hits) / float64(len(test))
+	}
+	result.MAP = meanAveragePrecision(pairs)
+	return result
+}
+
+// Площадь под ROC-кривой: вероятность, что случайное ребро оценено выше
+// случайной отрицательной пары (ничьи считаются за половину)
+func auc(sorted []scored) float64 {
+	positives, negatives := 0, 0
+	for _, p := range sorted {
+		if p.positive {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	if positives == 0 || negatives == 0 {
+		return 0
+	}
+
+	// Идём от худших оценок к лучшим группами равных оценок
+	wins := 0.0
+	below := 0 // Отрицательных с меньшей оценкой
+	for i := len(sorted) - 1; i >= 0; {
+		j := i
+		pos, neg := 0, 0
+		for ; j >= 0 && sorted[j].score == sorted[i].score; j-- {
+			if sorted[j].positive {
+				pos++
+			} else {
+				neg++
+			}
+		}
+		wins += float64(pos) * (float64(below) + float64(neg)/2)
+		below += neg
+		i = j
+	}
+	return wins / (float64(positives) * float64(negatives))
+}
+
+// Средняя точность по вершинам U, у которых есть хотя бы одно тестовое ребро
+func meanAveragePrecision(sorted []scored) float64 {
+	type state struct {
+		seen, hits int
+		sum        float64
+	}
+	users := make(map[int]*state)
+	for _, p := range sorted {
+		s := users[p.u]
+		if s == nil {
+			s = &state{}
+			users[p.u] = s
+		}
+		s.seen++
+		if p.positive {
+			s.hits++
+			s.sum += float64(s.hits) / float64(s.seen)
+		}
+	}
+
+	total, count := 0.0, 0
+	for _, s := range users {
+		if s.hits > 0 {
+			total += s.sum / float64(s.hits)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// Печатает результаты нескольких эвристик таблицей
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	k := 0
+	if len(results) > 0 {
+		k = results[0].K
+	}
+	fmt.Fprintf(tw, "Эвристика\tAUC\tP@%d\tR@%d\tMAP\n", k, k)
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%.4f\t%.4f\n", r.Name, r.AUC, r.PrecisionAtK, r.RecallAtK, r.MAP)
+	}
+	return tw.Flush()
+}
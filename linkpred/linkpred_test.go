@@ -0,0 +1,97 @@
+package linkpred
+
+import (
+	"math"
+	"testing"
+	"wintersc/graph"
+)
+
+// Оценки заданы таблицей. В общем рейтинге (+ — тестовое ребро):
+//
+//	(1,2)+ 0.9, (1,5)- 0.8, (2,4)+ 0.7, (1,6)- 0.4, (1,3)+ 0.4, (2,6)- 0.1
+//
+// AUC: (1,2) выше трёх отрицательных, (2,4) — двух, (1,3) выше одной и
+// вровень с одной, итого 6.5 из 9. AP вершины 1: (1/1 + 2/4) / 2 = 0.75,
+// вершины 2: 1, MAP = 0.875.
+func TestEvaluateHandComputed(t *testing.T) {
+	scores := map[Pair]float64{
+		{1, 2}: 0.9, {1, 3}: 0.4, {2, 4}: 0.7,
+		{1, 5}: 0.8, {1, 6}: 0.4, {2, 6}: 0.1,
+	}
+	scorer := ScorerFunc(func(g graph.View, u, v int) float64 { return scores[Pair{u, v}] })
+	test := []graph.Edge{{U: 1, V: 2}, {U: 1, V: 3}, {U: 2, V: 4}}
+	negatives := []Pair{{1, 5}, {1, 6}, {2, 6}}
+
+	tests := []struct {
+		k                 int
+		precision, recall float64
+	}{
+		{1, 1, 1.0 / 3},
+		{2, 0.5, 1.0 / 3},
+		{3, 2.0 / 3, 2.0 / 3},
+		// Пар меньше k: точность по всем шести
+		{10, 0.5, 1},
+	}
+	for _, tt := range tests {
+		r := Evaluate(graph.NewGraph(), test, negatives, scorer, tt.k)
+		if !near(r.AUC, 6.5/9) || !near(r.MAP, 0.875) {
+			t.Errorf("k = %d: AUC %.4f, MAP %.4f, ожидалось %.4f и 0.875", tt.k, r.AUC, r.MAP, 6.5/9)
+		}
+		if !near(r.PrecisionAtK, tt.precision) || !near(r.RecallAtK, tt.recall) {
+			t.Errorf("k = %d: P@k %.4f, R@k %.4f, ожидалось %.4f и %.4f",
+				tt.k, r.PrecisionAtK, r.RecallAtK, tt.precision, tt.recall)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// Копии кратного ребра не расходятся между обучением и тестом
+func TestSplitMultiEdges(t *testing.T) {
+	g := graph.NewGraph()
+	for u := 0; u < 30; u++ {
+		for copies := 0; copies <= u%3; copies++ {
+			g.AddEdgeAt(u, (u+1)%30, 1, int64(u))
+		}
+	}
+	for _, temporal := range []bool{false, true} {
+		train, test := Split(g, SplitOptions{TestFraction: 0.3, Temporal: temporal, Seed: 1})
+		if len(test) != 9 {
+			t.Errorf("Temporal %v: %d тестовых рёбер, ожидалось 9 из 30 пар", temporal, len(test))
+		}
+		seen := map[[2]int]bool{}
+		for _, edge := range test {
+			key := pairKey(edge.U, edge.V)
+			if seen[key] {
+				t.Errorf("Temporal %v: пара %v в тесте дважды", temporal, key)
+			}
+			seen[key] = true
+			if graph.HasEdge(train, edge.U, edge.V) {
+				t.Errorf("Temporal %v: тестовое ребро %v есть в обучающем графе", temporal, key)
+			}
+			// В тесте самые поздние пары
+			if temporal && edge.Created < 21 {
+				t.Errorf("Temporal: в тесте ребро %v с Created %d", key, edge.Created)
+			}
+		}
+		// Кратные рёбра обучающих пар переносятся целиком
+		for u := 0; u < 30; u++ {
+			if v := (u + 1) % 30; !seen[pairKey(u, v)] {
+				copies := 0
+				for w := range train.Neighbors(u) {
+					if w == v {
+						copies++
+					}
+				}
+				if copies != u%3+1 {
+					t.Errorf("Temporal %v: у пары (%d, %d) %d копий, ожидалось %d", temporal, u, v, copies, u%3+1)
+				}
+			}
+		}
+		if train.NumVertices() != g.NumVertices() {
+			t.Errorf("Temporal %v: в обучающем графе %d вершин", temporal, train.NumVertices())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package linkpred
+
+import (
+	"math"
+	"wintersc/graph"
+)
+
+// Оценка правдоподобия ребра (u, v) по обучающему графу: чем больше, тем вероятнее
+type Scorer interface {
+	Score(g graph.View, u, v int) float64
+}
+
+// Позволяет использовать обычную функцию как Scorer
+type ScorerFunc func(g graph.View, u, v int) float64
+
+func (f ScorerFunc) Score(g graph.View, u, v int) float64 {
+	return f(g, u, v)
+}
+
+// Встроенные эвристики по именам
+var Scorers = map[string]Scorer{
+	"common_neighbors":        ScorerFunc(CommonNeighbors),
+	"jaccard":                 ScorerFunc(Jaccard),
+	"adamic_adar":             ScorerFunc(AdamicAdar),
+	"resource_allocation":     ScorerFunc(ResourceAllocation),
+	"preferential_attachment": ScorerFunc(PreferentialAttachment),
+}
+
+func neighborSet(g graph.View, u int) map[int]bool {
+	set := make(map[int]bool, g.Degree(u))
+	for v := range g.Neighbors(u) {
+		set[v] = true
+	}
+	return set
+}
+
+// Общие соседи u и v
+func common(g graph.View, u, v int) []int {
+	set := neighborSet(g, u)
+	result := []int{}
+	for w := range g.Neighbors(v) {
+		if set[w] {
+			result = append(result, w)
+			delete(set, w) // Кратные рёбра не считаем дважды
+		}
+	}
+	return result
+}
+
+func CommonNeighbors(g graph.View, u, v int) float64 {
+	return float64(len(common(g, u, v)))
+}
+
+func Jaccard(g graph.View, u, v int) float64 {
+	union := neighborSet(g, u)
+	for w := range g.Neighbors(v) {
+		union[w] = true
+	}
+	if len(union) == 0 {
+		return 0
+	}
+	return CommonNeighbors(g, u, v) / float64(len(union))
+}
+
+func AdamicAdar(g graph.View, u, v int) float64 {
+	score := 0.0
+	for _, w := range common(g, u, v) {
+		if d := g.Degree(w); d > 1 {
+			score += 1 / math.Log(float64(d))
+		}
+	}
+	return score
+}
+
+func ResourceAllocation(g graph.View, u, v int) float64 {
+	score := 0.0
+	for _, w := range common(g, u, v) {
+		score += 1 / float64(g.Degree(w))
+	}
+	return score
+}
+
+func PreferentialAttachment(g graph.View, u, v int) float64 {
+	return float64(g.Degree(u) * g.Degree(v))
+}
@@ -0,0 +1,108 @@
+package linkpred
+
+import (
+	"math/rand"
+	"sort"
+	"wintersc/graph"
+)
+
+// Параметры разбиения рёбер на обучающие и тестовые
+type SplitOptions struct {
+	TestFraction float64 // Доля рёбер в тесте (0 — 0.1)
+	Temporal     bool    // В тест идут самые поздние рёбра по Created, иначе случайные
+	Seed         int64
+}
+
+// Разбивает неудалённые рёбра графа. Делятся пары вершин, а не рёбра:
+// все копии кратного ребра попадают в обучающий граф, а в тест — одно ребро
+// на пару, иначе тестовое ребро было бы видно при обучении. Обучающий граф
+// содержит все вершины исходного, чтобы у тестовых рёбер были концы.
+func Split(g *graph.Graph, opts SplitOptions) (train *graph.Graph, test []graph.Edge) {
+	if opts.TestFraction <= 0 {
+		opts.TestFraction = 0.1
+	}
+
+	// Для каждой пары запоминаем самое раннее ребро: по нему пара
+	// упорядочивается в Temporal и попадает в тест
+	first := make(map[[2]int]graph.Edge)
+	pairs := [][2]int{}
+	for _, edge := range g.Edge {
+		if edge.Deleted != 0 {
+			continue
+		}
+		key := pairKey(edge.U, edge.V)
+		if seen, ok := first[key]; !ok {
+			pairs = append(pairs, key)
+			first[key] = edge
+		} else if edge.Created < seen.Created {
+			first[key] = edge
+		}
+	}
+	if opts.Temporal {
+		// Устойчивая сортировка: пары с одинаковым временем остаются в порядке добавления
+		sort.SliceStable(pairs, func(i, j int) bool { return first[pairs[i]].Created < first[pairs[j]].Created })
+	} else {
+		rng := rand.New(rand.NewSource(opts.Seed))
+		rng.Shuffle(len(pairs), func(i, j int) { pairs[i], pairs[j] = pairs[j], pairs[i] })
+	}
+
+	cut := len(pairs) - int(float64(len(pairs))*opts.TestFraction)
+	inTest := make(map[[2]int]bool, len(pairs)-cut)
+	for _, key := range pairs[cut:] {
+		inTest[key] = true
+		test = append(test, first[key])
+	}
+	train = graph.NewGraph()
+	for u := range g.Adj {
+		train.AddVertex(u)
+	}
+	for _, edge := range g.Edge {
+		if edge.Deleted == 0 && !inTest[pairKey(edge.U, edge.V)] {
+			train.InsertEdge(edge)
+		}
+	}
+	return train, test
+}
+
+func pairKey(u, v int) [2]int {
+	if u > v {
+		u, v = v, u
+	}
+	return [2]int{u, v}
+}
+
+// Пара вершин-кандидатов на ребро
+type Pair struct {
+	U, V int
+}
+
+// Для каждого тестового ребра (u, v) выбирает perPositive вершин w, не
+// соединённых с u в полном графе g: пары (u, w) служат отрицательными
+// примерами. Группировка по u нужна для MAP.
+func NegativeSamples(g *graph.Graph, test []graph.Edge, perPositive int, seed int64) []Pair {
+	rng := rand.New(rand.NewSource(seed))
+	vertices := make([]int, 0, len(g.Adj))
+	for u := range g.Adj {
+		vertices = append(vertices, u)
+	}
+	sort.Ints(vertices)
+
+	negatives := []Pair{}
+	for _, edge := range test {
+		u := edge.U
+		// Вершина, связанная со всеми, не даёт отрицательных примеров
+		if g.Degree(u) >= len(vertices)-1 {
+			continue
+		}
+		for k := 0; k < perPositive; k++ {
+			for {
+				w := vertices[rng.Intn(len(vertices))]
+				if w != u && !graph.HasEdge(g, u, w) {
+					negatives = append(negatives, Pair{U: u, V: w})
+					break
+				}
+			}
+		}
+	}
+	return negatives
+}
@@ -0,0 +1,65 @@
+package sampling
+
+import (
+	"math/rand"
+	"sort"
+	"wintersc/graph"
+)
+
+// Общие части всех стратегий: выборка набирается как множество вершин,
+// а результатом служит порождённый ими подграф исходного графа.
+
+type sample struct {
+	rng      *rand.Rand
+	graph    *graph.Graph
+	vertices []int // Вершины графа по возрастанию, чтобы выборка зависела только от seed
+	chosen   map[int]bool
+	order    []int
+}
+
+func newSample(g *graph.Graph, seed int64) *sample {
+	vertices := make([]int, 0, len(g.Adj))
+	for u := range g.Adj {
+		vertices = append(vertices, u)
+	}
+	sort.Ints(vertices)
+	return &sample{
+		rng:      rand.New(rand.NewSource(seed)),
+		graph:    g,
+		vertices: vertices,
+		chosen:   make(map[int]bool),
+	}
+}
+
+func (s *sample) add(u int) {
+	if !s.chosen[u] {
+		s.chosen[u] = true
+		s.order = append(s.order, u)
+	}
+}
+
+func (s *sample) randomVertex() int {
+	return s.vertices[s.rng.Intn(len(s.vertices))]
+}
+
+// Случайная вершина, ещё не попавшая в выборку
+func (s *sample) randomUnchosen() int {
+	for {
+		if u := s.randomVertex(); !s.chosen[u] {
+			return u
+		}
+	}
+}
+
+func (s *sample) result() *graph.Graph {
+	return graph.Induced(s.graph, s.order).Materialize()
+}
+
+// Размер выборки от нуля до числа вершин
+func (s *sample) limit(k int) int {
+	return clamp(k, len(s.vertices))
+}
+
+func clamp(k, n int) int {
+	return max(0, min(k, n))
+}
@@ -0,0 +1,57 @@
+package sampling
+
+import (
+	"testing"
+	"wintersc/graph"
+)
+
+// Решётка 10 × 10 и одно удалённое ребро
+func samplingGraph() *graph.Graph {
+	g := graph.NewGraph()
+	for u := 0; u < 100; u++ {
+		if u%10 < 9 {
+			g.AddEdge(u, u+1, 1)
+		}
+		if u < 90 {
+			g.AddEdge(u, u+10, 1)
+		}
+	}
+	g.AddEdgeAt(0, 99, 1, 10)
+	g.RemoveEdgeAt(0, 99, 20)
+	return g
+}
+
+var strategies = map[string]func(g *graph.Graph, k int) *graph.Graph{
+	"UniformNodes":       func(g *graph.Graph, k int) *graph.Graph { return UniformNodes(g, k, 1) },
+	"UniformEdges":       func(g *graph.Graph, k int) *graph.Graph { return UniformEdges(g, k, 1) },
+	"Snowball":           func(g *graph.Graph, k int) *graph.Graph { return Snowball(g, k, 3, 1) },
+	"ForestFire":         func(g *graph.Graph, k int) *graph.Graph { return ForestFire(g, k, 0.7, 1) },
+	"ForestFire pf=1":    func(g *graph.Graph, k int) *graph.Graph { return ForestFire(g, k, 1, 1) },
+	"ForestFire pf<0":    func(g *graph.Graph, k int) *graph.Graph { return ForestFire(g, k, -1, 1) },
+	"RandomWalk":         func(g *graph.Graph, k int) *graph.Graph { return RandomWalkWithRestart(g, k, 0.15, 1) },
+	"MetropolisHastings": func(g *graph.Graph, k int) *graph.Graph { return MetropolisHastingsWalk(g, k, 1) },
+}
+
+func TestSampleSize(t *testing.T) {
+	g := samplingGraph()
+	for name, sample := range strategies {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []int{-5, 0, 20, 100, 1000} {
+				s := sample(g, k)
+				want := max(0, min(k, 100))
+				// UniformEdges берёт k рёбер, вершин у них может быть больше
+				if name == "UniformEdges" {
+					if (k <= 0) != (s.NumVertices() == 0) || s.NumVertices() > 100 {
+						t.Errorf("k = %d: %d вершин", k, s.NumVertices())
+					}
+				} else if s.NumVertices() != want {
+					t.Errorf("k = %d: %d вершин, ожидалось %d", k, s.NumVertices(), want)
+				}
+				// В выборку попадают только живые рёбра
+				if len(s.Edge) != s.NumEdges() || graph.HasEdge(s, 0, 99) {
+					t.Errorf("k = %d: в выборке удалённые рёбра: %d в истории, %d живых", k, len(s.Edge), s.NumEdges())
+				}
+			}
+		})
+	}
+}
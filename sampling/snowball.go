@@ -0,0 +1,89 @@
+package sampling
+
+import (
+	"wintersc/graph"
+)
+
+// Выборка «снежным комом»: обход в ширину от случайной вершины, при котором
+// из каждой вершины берётся не больше fanout случайных ещё не выбранных соседей.
+// Если ком перестал расти, начинаем новый из случайной вершины.
+func Snowball(g *graph.Graph, k, fanout int, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	k = s.limit(k)
+
+	for len(s.order) < k {
+		start := s.randomUnchosen()
+		s.add(start)
+		queue_slice := &graph.Queue{}
+		queue_slice.Enqueue(start)
+		for !queue_slice.IsEmpty() && len(s.order) < k {
+			u, _ := queue_slice.Dequeue()
+			fresh := []int{}
+			for _, v := range g.Adj[u] {
+				if !s.chosen[v] {
+					fresh = append(fresh, v)
+				}
+			}
+			s.rng.Shuffle(len(fresh), func(i, j int) { fresh[i], fresh[j] = fresh[j], fresh[i] })
+			if fanout > 0 && len(fresh) > fanout {
+				fresh = fresh[:fanout]
+			}
+			for _, v := range fresh {
+				if len(s.order) >= k {
+					break
+				}
+				if !s.chosen[v] {
+					s.add(v)
+					queue_slice.Enqueue(v)
+				}
+			}
+		}
+	}
+	return s.result()
+}
+
+// Модель «лесного пожара» (Leskovec, Faloutsos): из горящей вершины огонь
+// перекидывается на x ещё не горевших соседей, где x распределено
+// геометрически со средним pf/(1-pf). Когда пожар гаснет, поджигаем
+// новую случайную вершину. При pf >= 1 огонь перекидывается на всех
+// соседей, при pf <= 0 — ни на кого.
+func ForestFire(g *graph.Graph, k int, pf float64, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	k = s.limit(k)
+
+	for len(s.order) < k {
+		start := s.randomUnchosen()
+		s.add(start)
+		queue_slice := &graph.Queue{}
+		queue_slice.Enqueue(start)
+		for !queue_slice.IsEmpty() && len(s.order) < k {
+			u, _ := queue_slice.Dequeue()
+
+			fresh := []int{}
+			for _, v := range g.Adj[u] {
+				if !s.chosen[v] {
+					fresh = append(fresh, v)
+				}
+			}
+
+			// Число вершин, на которые перекинется огонь. Больше, чем
+			// несгоревших соседей, не нужно, иначе при pf >= 1 цикл не кончится.
+			burn := 0
+			for burn < len(fresh) && s.rng.Float64() < pf {
+				burn++
+			}
+
+			s.rng.Shuffle(len(fresh), func(i, j int) { fresh[i], fresh[j] = fresh[j], fresh[i] })
+			for _, v := range fresh[:burn] {
+				if len(s.order) >= k {
+					break
+				}
+				if !s.chosen[v] {
+					s.add(v)
+					queue_slice.Enqueue(v)
+				}
+			}
+		}
+	}
+	return s.result()
+}
@@ -0,0 +1,32 @@
+package sampling
+
+import (
+	"wintersc/graph"
+)
+
+// k вершин, выбранных равновероятно, и все рёбра между ними
+func UniformNodes(g *graph.Graph, k int, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	k = s.limit(k)
+	for _, i := range s.rng.Perm(len(s.vertices))[:k] {
+		s.add(s.vertices[i])
+	}
+	return s.result()
+}
+
+// k рёбер, выбранных равновероятно; результат — подграф, порождённый их концами
+func UniformEdges(g *graph.Graph, k int, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	alive := []graph.Edge{}
+	for _, edge := range g.Edge {
+		if edge.Deleted == 0 {
+			alive = append(alive, edge)
+		}
+	}
+	k = clamp(k, len(alive))
+	for _, i := range s.rng.Perm(len(alive))[:k] {
+		s.add(alive[i].U)
+		s.add(alive[i].V)
+	}
+	return s.result()
+}
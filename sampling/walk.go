@@ -0,0 +1,88 @@
+package sampling
+
+import (
+	"wintersc/graph"
+)
+
+// Если блуждание долго не находит новых вершин (маленькая компонента),
+// начинаем заново из случайной вершины
+const stuckSteps = 100
+
+// Случайное блуждание с возвратом: на каждом шаге с вероятностью restart
+// возвращаемся в стартовую вершину, иначе идём к случайному соседу.
+// Блуждание продолжается, пока не набрано k различных вершин.
+func RandomWalkWithRestart(g *graph.Graph, k int, restart float64, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	k = s.limit(k)
+	if k == 0 {
+		return s.result()
+	}
+
+	start := s.randomVertex()
+	current := start
+	s.add(current)
+	idle := 0
+	for len(s.order) < k {
+		if idle > stuckSteps*k {
+			start = s.randomUnchosen()
+			current = start
+			s.add(current)
+			idle = 0
+			continue
+		}
+
+		neighbors := g.Adj[current]
+		if len(neighbors) == 0 || s.rng.Float64() < restart {
+			current = start
+		} else {
+			current = neighbors[s.rng.Intn(len(neighbors))]
+		}
+
+		before := len(s.order)
+		s.add(current)
+		if len(s.order) == before {
+			idle++
+		} else {
+			idle = 0
+		}
+	}
+	return s.result()
+}
+
+// Блуждание Метрополиса–Гастингса: переход к соседу v принимается с
+// вероятностью min(1, deg(u)/deg(v)), поэтому стационарное распределение
+// равномерно по вершинам, а не пропорционально степени.
+func MetropolisHastingsWalk(g *graph.Graph, k int, seed int64) *graph.Graph {
+	s := newSample(g, seed)
+	k = s.limit(k)
+	if k == 0 {
+		return s.result()
+	}
+
+	current := s.randomVertex()
+	s.add(current)
+	idle := 0
+	for len(s.order) < k {
+		neighbors := g.Adj[current]
+		if len(neighbors) == 0 || idle > stuckSteps*k {
+			current = s.randomUnchosen()
+			s.add(current)
+			idle = 0
+			continue
+		}
+
+		candidate := neighbors[s.rng.Intn(len(neighbors))]
+		if s.rng.Float64()*float64(len(g.Adj[candidate])) <= float64(len(neighbors)) {
+			current = candidate
+		}
+
+		before := len(s.order)
+		s.add(current)
+		if len(s.order) == before {
+			idle++
+		} else {
+			idle = 0
+		}
+	}
+	return s.result()
+}
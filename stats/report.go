@@ -0,0 +1,295 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"text/tabwriter"
+	"wintersc/graph"
+)
+
+// Параметры профилирования
+type Options struct {
+	Samples int // Число источников BFS для средней длины пути (0 — 32)
+	Sweeps  int // Число двойных проходов для оценки диаметра (0 — 4)
+	Seed    int64
+}
+
+// Число вершин с данной степенью
+type DegreeCount struct {
+	Degree int `json:"degree"`
+	Count  int `json:"count"`
+}
+
+// Число компонент данного размера
+type SizeCount struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+}
+
+// Оценка степенного закона P(k) ~ k^-alpha для степеней k >= XMin
+type PowerLaw struct {
+	Alpha float64 `json:"alpha"`
+	XMin  int     `json:"xmin"`
+	KS    float64 `json:"ks"`   // Расстояние Колмогорова–Смирнова до подобранного распределения
+	Tail  int     `json:"tail"` // Число вершин со степенью не меньше XMin
+}
+
+// Профиль графа
+type Report struct {
+	Vertices        int           `json:"vertices"`
+	Edges           int           `json:"edges"`
+	Density         float64       `json:"density"`
+	MinDegree       int           `json:"min_degree"`
+	MaxDegree       int           `json:"max_degree"`
+	MeanDegree      float64       `json:"mean_degree"`
+	DegreeHistogram []DegreeCount `json:"degree_histogram"`
+	PowerLaw        PowerLaw      `json:"power_law"`
+	Components      int           `json:"components"`
+	LargestComp     int           `json:"largest_component"`
+	ComponentSizes  []SizeCount   `json:"component_sizes"`
+	DiameterLower   int           `json:"diameter_estimate"` // Нижняя оценка двойным проходом BFS
+	AvgPathLength   float64       `json:"avg_path_length"`   // По выборке источников
+	Assortativity   float64       `json:"degree_assortativity"`
+}
+
+// Собирает все метрики графа за один вызов
+func Profile(g graph.View, opts Options) *Report {
+	if opts.Samples <= 0 {
+		opts.Samples = 32
+	}
+	if opts.Sweeps <= 0 {
+		opts.Sweeps = 4
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	r := &Report{Vertices: g.NumVertices(), Edges: g.NumEdges()}
+	if r.Vertices > 1 {
+		r.Density = 2 * float64(r.Edges) / (float64(r.Vertices) * float64(r.Vertices-1))
+	}
+
+	// Вершины по возрастанию, чтобы результат зависел только от Seed
+	vertices := make([]int, 0, r.Vertices)
+	for u := range g.Vertices() {
+		vertices = append(vertices, u)
+	}
+	sort.Ints(vertices)
+
+	degrees := make([]int, 0, len(vertices))
+	for _, u := range vertices {
+		degrees = append(degrees, g.Degree(u))
+	}
+	r.degreeStats(degrees)
+	r.PowerLaw = FitPowerLaw(degrees)
+
+	count, comp := graph.ConnectedComponents(g)
+	r.Components = count
+	largest := r.componentStats(vertices, comp)
+
+	r.DiameterLower = estimateDiameter(g, largest, opts.Sweeps, rng)
+	r.AvgPathLength = averagePathLength(g, vertices, opts.Samples, rng)
+	r.Assortativity = Assortativity(g)
+	return r
+}
+
+func (r *Report) degreeStats(degrees []int) {
+	if len(degrees) == 0 {
+		return
+	}
+	histogram := make(map[int]int)
+	r.MinDegree = degrees[0]
+	sum := 0
+	for _, d := range degrees {
+		histogram[d]++
+		r.MinDegree = min(r.MinDegree, d)
+		r.MaxDegree = max(r.MaxDegree, d)
+		sum += d
+	}
+	r.MeanDegree = float64(sum) / float64(len(degrees))
+	for d, c := range histogram {
+		r.DegreeHistogram = append(r.DegreeHistogram, DegreeCount{Degree: d, Count: c})
+	}
+	sort.Slice(r.DegreeHistogram, func(i, j int) bool { return r.DegreeHistogram[i].Degree < r.DegreeHistogram[j].Degree })
+}
+
+// Заполняет распределение размеров компонент и возвращает вершины наибольшей.
+// Из равных по размеру берётся компонента с наименьшей вершиной: номера
+// компонент зависят от порядка обхода словаря. vertices отсортированы,
+// поэтому наименьшая вершина компоненты стоит в её списке первой.
+func (r *Report) componentStats(vertices []int, comp map[int]int) []int {
+	members := make(map[int][]int)
+	for _, u := range vertices {
+		members[comp[u]] = append(members[comp[u]], u)
+	}
+
+	sizes := make(map[int]int)
+	var largest []int
+	for _, list := range members {
+		sizes[len(list)]++
+		if len(list) > len(largest) || (len(list) == len(largest) && list[0] < largest[0]) {
+			largest = list
+		}
+	}
+	r.LargestComp = len(largest)
+	for size, c := range sizes {
+		r.ComponentSizes = append(r.ComponentSizes, SizeCount{Size: size, Count: c})
+	}
+	sort.Slice(r.ComponentSizes, func(i, j int) bool { return r.ComponentSizes[i].Size > r.ComponentSizes[j].Size })
+	return largest
+}
+
+// Эксцентриситет start и самая далёкая от неё вершина
+func farthest(g graph.View, start int) (vertex, depth int) {
+	vertex = start
+	for visit := range graph.BFSSeq(context.Background(), g, start, graph.TraversalOptions{}) {
+		if visit.Depth > depth {
+			vertex, depth = visit.Vertex, visit.Depth
+		}
+	}
+	return vertex, depth
+}
+
+// Двойной проход BFS: из случайной вершины к самой далёкой a, затем эксцентриситет a.
+// Это нижняя оценка диаметра, на реальных графах обычно точная.
+func estimateDiameter(g graph.View, component []int, sweeps int, rng *rand.Rand) int {
+	best := 0
+	for i := 0; i < sweeps && len(component) > 0; i++ {
+		start := component[rng.Intn(len(component))]
+		a, _ := farthest(g, start)
+		_, depth := farthest(g, a)
+		best = max(best, depth)
+	}
+	return best
+}
+
+// Средняя длина кратчайшего пути между достижимыми парами по выборке источников
+func averagePathLength(g graph.View, vertices []int, samples int, rng *rand.Rand) float64 {
+	if len(vertices) == 0 {
+		return 0
+	}
+	total, pairs := 0, 0
+	for _, i := range rng.Perm(len(vertices))[:min(samples, len(vertices))] {
+		for visit := range graph.BFSSeq(context.Background(), g, vertices[i], graph.TraversalOptions{}) {
+			if visit.Depth > 0 {
+				total += visit.Depth
+				pairs++
+			}
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return float64(total) / float64(pairs)
+}
+
+// Коэффициент ассортативности по степеням (Newman, 2002): корреляция
+// степеней концов рёбер, от -1 (хабы связаны с листьями) до 1
+func Assortativity(g graph.View) float64 {
+	var m, sumProduct, sumHalf, sumSquares float64
+	for edge := range graph.Edges(g) {
+		j, k := float64(g.Degree(edge.U)), float64(g.Degree(edge.V))
+		m++
+		sumProduct += j * k
+		sumHalf += (j + k) / 2
+		sumSquares += (j*j + k*k) / 2
+	}
+	if m == 0 {
+		return 0
+	}
+	mean := sumHalf / m
+	denominator := sumSquares/m - mean*mean
+	if denominator == 0 {
+		return 0
+	}
+	return (sumProduct/m - mean*mean) / denominator
+}
+
+// Подбор степенного закона методом максимального правдоподобия (Clauset,
+// Shalizi, Newman, 2009): для каждого кандидата XMin оцениваем alpha
+// и выбираем XMin с наименьшим расстоянием Колмогорова–Смирнова
+func FitPowerLaw(degrees []int) PowerLaw {
+	sorted := []int{}
+	for _, d := range degrees {
+		if d > 0 {
+			sorted = append(sorted, d)
+		}
+	}
+	sort.Ints(sorted)
+
+	best := PowerLaw{KS: math.Inf(1)}
+	for i := 0; i < len(sorted); i++ {
+		if i > 0 && sorted[i] == sorted[i-1] {
+			continue
+		}
+		tail := sorted[i:]
+		// Слишком короткий хвост не даёт осмысленной оценки
+		if len(tail) < 10 {
+			break
+		}
+		xmin := float64(sorted[i])
+		logSum := 0.0
+		for _, x := range tail {
+			logSum += math.Log(float64(x) / (xmin - 0.5))
+		}
+		if logSum == 0 {
+			continue
+		}
+		alpha := 1 + float64(len(tail))/logSum
+
+		// Сравниваем эмпирическую и подобранную функции распределения
+		ks := 0.0
+		for j := 0; j < len(tail); j++ {
+			if j+1 < len(tail) && tail[j+1] == tail[j] {
+				continue
+			}
+			empirical := float64(j+1) / float64(len(tail))
+			fitted := 1 - math.Pow((float64(tail[j])+0.5)/(xmin-0.5), 1-alpha)
+			ks = max(ks, math.Abs(empirical-fitted))
+		}
+		if ks < best.KS {
+			best = PowerLaw{Alpha: alpha, XMin: sorted[i], KS: ks, Tail: len(tail)}
+		}
+	}
+	if math.IsInf(best.KS, 1) {
+		return PowerLaw{}
+	}
+	return best
+}
+
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Печатает отчёт в виде таблицы
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rows := [][2]string{
+		{"Вершин", fmt.Sprint(r.Vertices)},
+		{"Рёбер", fmt.Sprint(r.Edges)},
+		{"Плотность", fmt.Sprintf("%.6f", r.Density)},
+		{"Степень (мин/сред/макс)", fmt.Sprintf("%d / %.2f / %d", r.MinDegree, r.MeanDegree, r.MaxDegree)},
+		{"Степенной закон", fmt.Sprintf("alpha=%.3f xmin=%d KS=%.4f (хвост %d)", r.PowerLaw.Alpha, r.PowerLaw.XMin, r.PowerLaw.KS, r.PowerLaw.Tail)},
+		{"Компонент связности", fmt.Sprint(r.Components)},
+		{"Наибольшая компонента", fmt.Sprint(r.LargestComp)},
+		{"Диаметр (оценка)", fmt.Sprint(r.DiameterLower)},
+		{"Средняя длина пути", fmt.Sprintf("%.3f", r.AvgPathLength)},
+		{"Ассортативность", fmt.Sprintf("%.4f", r.Assortativity)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+	}
+
+	fmt.Fprintln(tw, "\nСтепень\tВершин")
+	for _, dc := range r.DegreeHistogram {
+		fmt.Fprintf(tw, "%d\t%d\n", dc.Degree, dc.Count)
+	}
+	fmt.Fprintln(tw, "\nРазмер компоненты\tКоличество")
+	for _, sc := range r.ComponentSizes {
+		fmt.Fprintf(tw, "%d\t%d\n", sc.Size, sc.Count)
+	}
+	return tw.Flush()
+}
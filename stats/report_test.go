@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// Две компоненты одного размера: наибольшей считается та, где меньше
+// наименьшая вершина, как бы ни были занумерованы компоненты
+func TestLargestComponentTieBreak(t *testing.T) {
+	g := graph.NewGraph()
+	g.AddEdge(10, 11, 1)
+	g.AddEdge(11, 12, 1)
+	g.AddEdge(-3, 20, 1)
+	g.AddEdge(20, 30, 1)
+	g.AddVertex(5)
+	vertices := []int{-3, 5, 10, 11, 12, 20, 30}
+
+	for i := 0; i < 20; i++ {
+		_, comp := graph.ConnectedComponents(g)
+		r := &Report{}
+		largest := r.componentStats(vertices, comp)
+		if !reflect.DeepEqual(largest, []int{-3, 20, 30}) {
+			t.Fatalf("наибольшая компонента %v, ожидалась [-3 20 30]", largest)
+		}
+		if r.LargestComp != 3 {
+			t.Errorf("LargestComp %d, ожидалось 3", r.LargestComp)
+		}
+		want := []SizeCount{{Size: 3, Count: 2}, {Size: 1, Count: 1}}
+		if !reflect.DeepEqual(r.ComponentSizes, want) {
+			t.Errorf("ComponentSizes %v, ожидалось %v", r.ComponentSizes, want)
+		}
+	}
+}
+
+// Профиль не зависит от порядка обхода словарей при одном Seed
+func TestProfileDeterministic(t *testing.T) {
+	g := graph.NewGraph()
+	for c := 0; c < 4; c++ {
+		for i := 0; i < 5; i++ {
+			g.AddEdge(c*10+i, c*10+(i+1)%5, 1)
+		}
+	}
+	first := Profile(g, Options{Seed: 7})
+	for i := 0; i < 10; i++ {
+		if r := Profile(g, Options{Seed: 7}); !reflect.DeepEqual(r, first) {
+			t.Fatalf("профиль изменился между запусками:\n%+v\n%+v", first, r)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"sort"
+	"wintersc/graph"
+)
+
+// Двоичный формат графа (все числа little-endian):
+//
+//	заголовок   magic "WGRB", версия uint32, n, m, slots, adjBytes uint64,
+//	            crc32 заголовка uint32, выравнивание uint32
+//	IDs         n × int64 — ID вершин по возрастанию (плотный индекс = позиция)
+//	EdgeOffsets (n+1) × uint64 — начало соседей вершины в массиве весов
+//	ByteOffsets (n+1) × uint64 — начало соседей вершины в блоке смежности
+//	Adjacency   adjBytes байт — плотные индексы соседей по возрастанию,
+//	            первый как uvarint, остальные как uvarint разности с предыдущим
+//	Weights     slots × int64 — веса в том же порядке, что и соседи
+//	crc32       uint32 — контрольная сумма всего, что выше
+//
+// Все секции, кроме смежности, имеют фиксированную ширину, поэтому
+// отображённый в память файл читается без предварительного разбора.
+const (
+	binaryMagic   = "WGRB"
+	binaryVersion = 1
+	binaryHeader  = 48
+)
+
+var ErrBadFormat = errors.New("storage: bad binary graph")
+
+// Записывает граф в двоичном формате
+func WriteBinary(w io.Writer, g graph.View) error {
+	ids := make([]int, 0, g.NumVertices())
+	for u := range g.Vertices() {
+		ids = append(ids, u)
+	}
+	sort.Ints(ids)
+	index := make(map[int]int, len(ids))
+	for i, u := range ids {
+		index[u] = i
+	}
+
+	type neighbor struct{ v, w int }
+	edgeOffsets := make([]uint64, len(ids)+1)
+	byteOffsets := make([]uint64, len(ids)+1)
+	var adjacency []byte
+	var weights []int64
+	for i, u := range ids {
+		var list []neighbor
+		for v, w := range g.Neighbors(u) {
+			list = append(list, neighbor{index[v], w})
+		}
+		sort.Slice(list, func(a, b int) bool { return list[a].v < list[b].v })
+
+		prev := 0
+		for _, nb := range list {
+			adjacency = binary.AppendUvarint(adjacency, uint64(nb.v-prev))
+			weights = append(weights, int64(nb.w))
+			prev = nb.v
+		}
+		edgeOffsets[i+1] = uint64(len(weights))
+		byteOffsets[i+1] = uint64(len(adjacency))
+	}
+
+	header := make([]byte, 0, binaryHeader)
+	header = append(header, binaryMagic...)
+	header = binary.LittleEndian.AppendUint32(header, binaryVersion)
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(ids)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(g.NumEdges()))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(weights)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(adjacency)))
+	header = binary.LittleEndian.AppendUint32(header, crc32.ChecksumIEEE(header))
+	header = binary.LittleEndian.AppendUint32(header, 0)
+
+	sum := crc32.NewIEEE()
+	out := bufio.NewWriter(io.MultiWriter(w, sum))
+	out.Write(header)
+	var buf [8]byte
+	for _, u := range ids {
+		binary.LittleEndian.PutUint64(buf[:], uint64(int64(u)))
+		out.Write(buf[:])
+	}
+	for _, offsets := range [][]uint64{edgeOffsets, byteOffsets} {
+		for _, off := range offsets {
+			binary.LittleEndian.PutUint64(buf[:], off)
+			out.Write(buf[:])
+		}
+	}
+	out.Write(adjacency)
+	for _, wt := range weights {
+		binary.LittleEndian.PutUint64(buf[:], uint64(wt))
+		out.Write(buf[:])
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	_, err := w.Write(binary.LittleEndian.AppendUint32(nil, sum.Sum32()))
+	return err
+}
+
+// Записывает граф в файл и сбрасывает его на диск
+func WriteBinaryFile(path string, g graph.View) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteBinary(file, g); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Граф только для чтения поверх отображённого в память файла.
+// Страницы файла разделяются между процессами через кэш ОС.
+type MappedGraph struct {
+	data        []byte
+	n, m, slots int
+	ids         []byte
+	edgeOffsets []byte
+	byteOffsets []byte
+	adjacency   []byte
+	weights     []byte
+	unmap       func() error
+}
+
+// Открывает файл в двоичном формате. Проверяются заголовок, размеры секций
+// и таблицы смещений: они должны не убывать и не выходить за свои секции,
+// иначе ErrBadFormat. Смежность и веса не читаются, полную контрольную
+// сумму проверяет Verify.
+func OpenBinary(path string) (*MappedGraph, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := parseBinary(data)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	g.unmap = unmap
+	return g, nil
+}
+
+func parseBinary(data []byte) (*MappedGraph, error) {
+	if len(data) < binaryHeader+4 || string(data[:4]) != binaryMagic {
+		return nil, ErrBadFormat
+	}
+	if binary.LittleEndian.Uint32(data[4:]) != binaryVersion {
+		return nil, ErrBadFormat
+	}
+	if crc32.ChecksumIEEE(data[:40]) != binary.LittleEndian.Uint32(data[40:]) {
+		return nil, ErrBadFormat
+	}
+	n := binary.LittleEndian.Uint64(data[8:])
+	m := binary.LittleEndian.Uint64(data[16:])
+	slots := binary.LittleEndian.Uint64(data[24:])
+	adjBytes := binary.LittleEndian.Uint64(data[32:])
+
+	// Проверяем размеры до умножения, чтобы испорченный заголовок не вызвал переполнение
+	limit := uint64(len(data))
+	if n > limit/8 || slots > limit/8 || adjBytes > limit {
+		return nil, ErrBadFormat
+	}
+	expected := binaryHeader + 8*n + 16*(n+1) + adjBytes + 8*slots + 4
+	if expected != limit {
+		return nil, ErrBadFormat
+	}
+
+	g := &MappedGraph{data: data, n: int(n), m: int(m), slots: int(slots)}
+	pos := uint64(binaryHeader)
+	section := func(size uint64) []byte {
+		s := data[pos : pos+size]
+		pos += size
+		return s
+	}
+	g.ids = section(8 * n)
+	g.edgeOffsets = section(8 * (n + 1))
+	g.byteOffsets = section(8 * (n + 1))
+	g.adjacency = section(adjBytes)
+	g.weights = section(8 * slots)
+	if !monotone(g.edgeOffsets, slots) || !monotone(g.byteOffsets, adjBytes) {
+		return nil, ErrBadFormat
+	}
+	return g, nil
+}
+
+// Смещения начинаются с нуля, не убывают и заканчиваются на end
+func monotone(offsets []byte, end uint64) bool {
+	prev := uint64(0)
+	for i := 0; i < len(offsets); i += 8 {
+		off := binary.LittleEndian.Uint64(offsets[i:])
+		if off < prev || (i == 0 && off != 0) {
+			return false
+		}
+		prev = off
+	}
+	return prev == end
+}
+
+// Проверяет контрольную сумму всего файла
+func (g *MappedGraph) Verify() error {
+	body := g.data[:len(g.data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(g.data[len(g.data)-4:]) {
+		return ErrBadFormat
+	}
+	return nil
+}
+
+// Освобождает отображение. После Close граф использовать нельзя.
+func (g *MappedGraph) Close() error {
+	if g.unmap == nil {
+		return nil
+	}
+	err := g.unmap()
+	g.unmap = nil
+	return err
+}
+
+func (g *MappedGraph) id(i int) int {
+	return int(int64(binary.LittleEndian.Uint64(g.ids[8*i:])))
+}
+
+func offset(section []byte, i int) int {
+	return int(binary.LittleEndian.Uint64(section[8*i:]))
+}
+
+// Плотный индекс вершины: бинарный поиск по отсортированной таблице ID
+func (g *MappedGraph) index(u int) (int, bool) {
+	i := sort.Search(g.n, func(i int) bool { return g.id(i) >= u })
+	return i, i < g.n && g.id(i) == u
+}
+
+func (g *MappedGraph) Vertices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < g.n; i++ {
+			if !yield(g.id(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (g *MappedGraph) Neighbors(u int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		i, exists := g.index(u)
+		if !exists {
+			return
+		}
+		// Смещения проверены в OpenBinary, а содержимое смежности — нет
+		from, to := offset(g.byteOffsets, i), offset(g.byteOffsets, i+1)
+		slot, last := offset(g.edgeOffsets, i), offset(g.edgeOffsets, i+1)
+		adjacency := g.adjacency[from:to]
+		target := 0
+		for ; slot < last; slot++ {
+			delta, n := binary.Uvarint(adjacency)
+			if n <= 0 {
+				return
+			}
+			adjacency = adjacency[n:]
+			target += int(delta)
+			if target >= g.n {
+				return
+			}
+			w := int(int64(binary.LittleEndian.Uint64(g.weights[8*slot:])))
+			if !yield(g.id(target), w) {
+				return
+			}
+		}
+	}
+}
+
+func (g *MappedGraph) Degree(u int) int {
+	i, exists := g.index(u)
+	if !exists {
+		return 0
+	}
+	return offset(g.edgeOffsets, i+1) - offset(g.edgeOffsets, i)
+}
+
+func (g *MappedGraph) HasVertex(u int) bool {
+	_, exists := g.index(u)
+	return exists
+}
+
+func (g *MappedGraph) NumVertices() int {
+	return g.n
+}
+
+func (g *MappedGraph) NumEdges() int {
+	return g.m
+}
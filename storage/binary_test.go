@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// Граф с петлёй, кратным ребром, отрицательными ID и весами и изолированной вершиной
+func binaryGraph() *graph.Graph {
+	g := graph.NewGraph()
+	g.AddEdge(1, 2, 4)
+	g.AddEdge(2, 3, -1)
+	g.AddEdge(-7, 1, 300)
+	g.AddEdge(3, 3, 2)
+	g.AddEdge(1, 2, 4)
+	g.AddVertex(1000)
+	return g
+}
+
+func neighbors(g graph.View, u int) map[int][]int {
+	result := map[int][]int{}
+	for v, w := range g.Neighbors(u) {
+		result[v] = append(result[v], w)
+	}
+	return result
+}
+
+func writeBinaryGraph(t *testing.T, g graph.View) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "graph.bin")
+	if err := WriteBinaryFile(path, g); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	g := binaryGraph()
+	m, err := OpenBinary(writeBinaryGraph(t, g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if m.NumVertices() != g.NumVertices() || m.NumEdges() != g.NumEdges() {
+		t.Errorf("%d вершин и %d рёбер, ожидалось %d и %d", m.NumVertices(), m.NumEdges(), g.NumVertices(), g.NumEdges())
+	}
+	for u := range g.Vertices() {
+		if !m.HasVertex(u) || m.Degree(u) != g.Degree(u) {
+			t.Errorf("вершина %d: есть %v, степень %d, ожидалась %d", u, m.HasVertex(u), m.Degree(u), g.Degree(u))
+		}
+		if got, want := neighbors(m, u), neighbors(g, u); !reflect.DeepEqual(got, want) {
+			t.Errorf("соседи %d: %v, ожидалось %v", u, got, want)
+		}
+	}
+	if m.HasVertex(5) || m.Degree(5) != 0 {
+		t.Error("найдена несуществующая вершина 5")
+	}
+
+	empty, err := OpenBinary(writeBinaryGraph(t, graph.NewGraph()))
+	if err != nil {
+		t.Fatalf("пустой граф: %v", err)
+	}
+	defer empty.Close()
+	if empty.NumVertices() != 0 {
+		t.Errorf("пустой граф: %d вершин", empty.NumVertices())
+	}
+}
+
+func TestBinaryCorrupt(t *testing.T) {
+	path := writeBinaryGraph(t, binaryGraph())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := int(binary.LittleEndian.Uint64(data[8:]))
+	edgeOffsets := binaryHeader + 8*n
+	byteOffsets := edgeOffsets + 8*(n+1)
+
+	setOffset := func(section, i int, value uint64) func([]byte) []byte {
+		return func(b []byte) []byte {
+			binary.LittleEndian.PutUint64(b[section+8*i:], value)
+			return b
+		}
+	}
+	tests := []struct {
+		name    string
+		corrupt func([]byte) []byte
+	}{
+		{"magic", func(b []byte) []byte { b[0] = 'X'; return b }},
+		{"заголовок", func(b []byte) []byte { b[8]++; return b }},
+		{"обрезан", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"лишний байт", func(b []byte) []byte { return append(b, 0) }},
+		{"первое смещение", setOffset(edgeOffsets, 0, 1)},
+		{"смещения убывают", setOffset(edgeOffsets, 1, 1<<40)},
+		{"смещение за концом", setOffset(byteOffsets, n, 1<<62)},
+		{"смещение байт убывает", setOffset(byteOffsets, 2, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupted := filepath.Join(t.TempDir(), "graph.bin")
+			if err := os.WriteFile(corrupted, tt.corrupt(append([]byte(nil), data...)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := OpenBinary(corrupted)
+			if err == nil {
+				m.Close()
+			}
+			if !errors.Is(err, ErrBadFormat) {
+				t.Errorf("OpenBinary: ожидалась ErrBadFormat, получено %v", err)
+			}
+		})
+	}
+
+	// Испорченный вес не мешает открытию, его находит Verify
+	data[len(data)-5] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenBinary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Verify(); !errors.Is(err, ErrBadFormat) {
+		t.Errorf("Verify: ожидалась ErrBadFormat, получено %v", err)
+	}
+}
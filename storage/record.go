@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"wintersc/graph"
+)
+
+// Тип операции над графом
+type Op byte
+
+const (
+	OpAddVertex Op = iota + 1
+	OpAddEdge
+	OpRemoveEdge
+	OpRemoveEdgeAt
+	OpInsertEdge // Ребро со всей историей, используется в снимках
+)
+
+// Одна операция журнала. Time — момент создания для OpAddEdge
+// и момент удаления для OpRemoveEdgeAt.
+type Record struct {
+	Op      Op
+	U, V, W int
+	Time    int64
+	Deleted int64 // Только для OpInsertEdge
+}
+
+var (
+	ErrCorrupt = errors.New("storage: corrupt record")
+	ErrUnknown = errors.New("storage: unknown operation")
+)
+
+// Размер заголовка записи: длина и контрольная сумма полезной нагрузки
+const headerSize = 8
+
+// Применяет операцию к графу
+func (r Record) Apply(g *graph.Graph) error {
+	switch r.Op {
+	case OpAddVertex:
+		g.AddVertex(r.U)
+	case OpAddEdge:
+		g.AddEdgeAt(r.U, r.V, r.W, r.Time)
+	case OpRemoveEdge:
+		g.RemoveEdge(r.U, r.V)
+	case OpRemoveEdgeAt:
+		g.RemoveEdgeAt(r.U, r.V, r.Time)
+	case OpInsertEdge:
+		g.InsertEdge(graph.Edge{U: r.U, V: r.V, W: r.W, Created: r.Time, Deleted: r.Deleted})
+	default:
+		return ErrUnknown
+	}
+	return nil
+}
+
+// Кодирует запись в кадр: [длина uint32][crc32 uint32][нагрузка]
+func appendRecord(buf []byte, r Record) []byte {
+	payload := []byte{byte(r.Op)}
+	payload = binary.AppendVarint(payload, int64(r.U))
+	payload = binary.AppendVarint(payload, int64(r.V))
+	payload = binary.AppendVarint(payload, int64(r.W))
+	payload = binary.AppendVarint(payload, r.Time)
+	payload = binary.AppendVarint(payload, r.Deleted)
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(payload))
+	return append(buf, payload...)
+}
+
+func decodePayload(payload []byte) (Record, error) {
+	if len(payload) == 0 {
+		return Record{}, ErrCorrupt
+	}
+	r := Record{Op: Op(payload[0])}
+	fields := make([]int64, 5)
+	rest := payload[1:]
+	for i := range fields {
+		value, n := binary.Varint(rest)
+		if n <= 0 {
+			return Record{}, ErrCorrupt
+		}
+		fields[i] = value
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return Record{}, ErrCorrupt
+	}
+	r.U, r.V, r.W = int(fields[0]), int(fields[1]), int(fields[2])
+	r.Time, r.Deleted = fields[3], fields[4]
+	return r, nil
+}
+
+// Читает записи подряд, пока они целы. Возвращает прочитанные записи и длину
+// корректного префикса. Испорченной может быть только последняя запись —
+// её оборвал сбой посреди записи, и всё начиная с неё отбрасывается. Если же
+// за испорченной записью есть ещё данные, это повреждение файла, а не
+// оборванный хвост, и readRecords возвращает ErrCorrupt.
+func readRecords(r io.Reader) ([]Record, int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []Record
+	offset := 0
+	for len(data)-offset >= headerSize {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		sum := binary.LittleEndian.Uint32(data[offset+4:])
+		end := offset + headerSize + size
+		if size == 0 || end > len(data) {
+			// Оборванная запись уходит за конец файла, а хвост,
+			// который файловая система не успела записать, состоит из нулей
+			if end > len(data) || isZero(data[offset:]) {
+				break
+			}
+			return nil, 0, fmt.Errorf("%w at offset %d", ErrCorrupt, offset)
+		}
+		payload := data[offset+headerSize : end]
+		record, err := decodePayload(payload)
+		if crc32.ChecksumIEEE(payload) != sum || err != nil {
+			if end == len(data) {
+				break
+			}
+			return nil, 0, fmt.Errorf("%w at offset %d", ErrCorrupt, offset)
+		}
+		records = append(records, record)
+		offset = end
+	}
+	return records, int64(offset), nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
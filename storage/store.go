@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"wintersc/graph"
+)
+
+// Долговечное хранилище графа: журнал операций плюс периодические снимки.
+//
+// Файлы в каталоге:
+//
+//	snapshot        — сжатое состояние графа и номер поколения G
+//	wal-<G>.log     — операции, выполненные после снимка поколения G
+//
+// Уплотнение пишет новый снимок с поколением G+1 во временный файл,
+// атомарно переименовывает его и только потом начинает журнал wal-<G+1>.
+// Поэтому при сбое на любом шаге на диске есть согласованная пара
+// "снимок + его журнал".
+//
+// Если уплотнение не удалось уже после переименования снимка, восстановление
+// будет читать журнал нового поколения, а не тот, в который пишет Store.
+// Такое хранилище помечается сломанным: все изменения возвращают ErrFailed,
+// и его нужно закрыть и открыть заново.
+type Store struct {
+	// Уплотнять журнал автоматически после стольких операций (0 — только вручную)
+	SnapshotEvery int
+
+	dir     string
+	gen     uint64
+	log     *Log
+	graph   *graph.Graph
+	pending int   // Операций в журнале после последнего снимка
+	failed  error // Ошибка, после которой запись в хранилище невозможна
+	mutex   sync.Mutex
+}
+
+const snapshotMagic = "WGSNAP01"
+
+var (
+	ErrBadSnapshot = errors.New("storage: corrupt snapshot")
+	ErrFailed      = errors.New("storage: store failed, reopen it")
+)
+
+// Открывает хранилище и восстанавливает граф: загружает последний снимок
+// и применяет к нему журнал. Оборванная последняя запись отбрасывается,
+// а испорченная запись в середине журнала — ошибка ErrCorrupt.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	g, gen, err := readSnapshot(filepath.Join(dir, "snapshot"))
+	if err != nil {
+		return nil, err
+	}
+
+	log, records, err := OpenLog(logPath(dir, gen))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if err := r.Apply(g); err != nil {
+			log.Close()
+			return nil, err
+		}
+	}
+
+	removeStaleLogs(dir, gen)
+	return &Store{dir: dir, gen: gen, log: log, graph: g, pending: len(records)}, nil
+}
+
+// Текущий граф. Менять его можно только через методы Store,
+// иначе изменения не попадут в журнал. Store меняет этот же граф
+// без копирования, поэтому читать его, в том числе через Clone,
+// можно только пока никто не пишет в хранилище.
+func (s *Store) Graph() *graph.Graph {
+	return s.graph
+}
+
+func (s *Store) AddVertex(u int) error {
+	return s.apply(Record{Op: OpAddVertex, U: u})
+}
+
+func (s *Store) AddEdge(u, v, w int) error {
+	return s.apply(Record{Op: OpAddEdge, U: u, V: v, W: w})
+}
+
+func (s *Store) AddEdgeAt(u, v, w int, t int64) error {
+	return s.apply(Record{Op: OpAddEdge, U: u, V: v, W: w, Time: t})
+}
+
+func (s *Store) RemoveEdge(u, v int) error {
+	return s.apply(Record{Op: OpRemoveEdge, U: u, V: v})
+}
+
+func (s *Store) RemoveEdgeAt(u, v int, t int64) error {
+	return s.apply(Record{Op: OpRemoveEdgeAt, U: u, V: v, Time: t})
+}
+
+// Сначала запись в журнал, затем изменение графа в памяти
+func (s *Store) apply(r Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failed != nil {
+		return s.failed
+	}
+	if err := s.log.Append(r); err != nil {
+		return err
+	}
+	if err := r.Apply(s.graph); err != nil {
+		return err
+	}
+	s.pending++
+	if s.SnapshotEvery > 0 && s.pending >= s.SnapshotEvery {
+		// Операция уже в журнале и в графе, поэтому ошибка уплотнения к ней
+		// не относится. Сбой до переименования снимка повторит уплотнение
+		// при следующей записи, а после него следующая запись вернёт ErrFailed.
+		s.compact()
+	}
+	return nil
+}
+
+// Записывает снимок текущего состояния и начинает пустой журнал
+func (s *Store) Snapshot() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failed != nil {
+		return s.failed
+	}
+	return s.compact()
+}
+
+func (s *Store) compact() error {
+	next := s.gen + 1
+	// Ошибка до переименования оставляет на диске прежний снимок,
+	// и можно продолжать писать в прежний журнал
+	if err := writeSnapshot(s.dir, s.graph, next); err != nil {
+		return err
+	}
+
+	// Снимок уже на диске, старый журнал больше не нужен. Но и писать
+	// в него больше нельзя: восстановление его не прочитает.
+	err := failpoint("wal-open")
+	var log *Log
+	if err == nil {
+		log, _, err = OpenLog(logPath(s.dir, next))
+	}
+	if err != nil {
+		s.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+		return s.failed
+	}
+	if err = failpoint("dir-sync"); err == nil {
+		err = syncDir(s.dir)
+	}
+	if err != nil {
+		// Без сброса каталога переименование может откатиться при сбое,
+		// и тогда пропадут записи нового журнала
+		log.Close()
+		s.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+		return s.failed
+	}
+	s.log.Close()
+	os.Remove(logPath(s.dir, s.gen))
+	s.log = log
+	s.gen = next
+	s.pending = 0
+	return nil
+}
+
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.log.Close()
+}
+
+// Точка отказа для тестов: вызывается перед каждым шагом уплотнения
+// и перед fsync журнала, ошибка прерывает операцию на этом шаге
+var failpoint = func(step string) error { return nil }
+
+func logPath(dir string, gen uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("wal-%d.log", gen))
+}
+
+// Удаляет журналы старых поколений, оставшиеся после сбоя во время уплотнения
+func removeStaleLogs(dir string, gen uint64) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	for _, path := range matches {
+		var other uint64
+		if _, err := fmt.Sscanf(filepath.Base(path), "wal-%d.log", &other); err == nil && other < gen {
+			os.Remove(path)
+		}
+	}
+}
+
+// Формат снимка: magic, поколение (uint64), записи в формате журнала, crc32 всего предыдущего
+func writeSnapshot(dir string, g *graph.Graph, gen uint64) error {
+	buf := []byte(snapshotMagic)
+	buf = binary.LittleEndian.AppendUint64(buf, gen)
+	buf = appendGraph(buf, g)
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+
+	tmp := filepath.Join(dir, "snapshot.tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := failpoint("snapshot-write"); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		file.Close()
+		return err
+	}
+	if err := failpoint("snapshot-sync"); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := failpoint("snapshot-rename"); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "snapshot"))
+}
+
+// Читает снимок. Если его нет, возвращает пустой граф поколения 0.
+func readSnapshot(path string) (*graph.Graph, uint64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return graph.NewGraph(), 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	header := len(snapshotMagic) + 8
+	if len(data) < header+4 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, 0, ErrBadSnapshot
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, 0, ErrBadSnapshot
+	}
+	gen := binary.LittleEndian.Uint64(data[len(snapshotMagic):])
+
+	g, err := decodeGraph(body[header:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, gen, nil
+}
+
+// Дописывает граф со всей историей рёбер как последовательность записей
+func appendGraph(buf []byte, g *graph.Graph) []byte {
+	// Вершины в порядке возрастания, чтобы результат был детерминированным
+	vertices := make([]int, 0, len(g.Adj))
+	for u := range g.Adj {
+		vertices = append(vertices, u)
+	}
+	sort.Ints(vertices)
+	for _, u := range vertices {
+		buf = appendRecord(buf, Record{Op: OpAddVertex, U: u})
+	}
+	for _, e := range g.Edge {
+		buf = appendRecord(buf, Record{Op: OpInsertEdge, U: e.U, V: e.V, W: e.W, Time: e.Created, Deleted: e.Deleted})
+	}
+	return buf
+}
+
+func decodeGraph(data []byte) (*graph.Graph, error) {
+	records, valid, err := readRecords(bytes.NewReader(data))
+	if err != nil || valid != int64(len(data)) {
+		return nil, ErrBadSnapshot
+	}
+
+	g := graph.NewGraph()
+	for _, r := range records {
+		if err := r.Apply(g); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Кодирует граф вместе с историей рёбер в формате записей журнала
+func MarshalGraph(g *graph.Graph) []byte {
+	return appendGraph(nil, g)
+}
+
+// Восстанавливает граф, закодированный MarshalGraph
+func UnmarshalGraph(data []byte) (*graph.Graph, error) {
+	return decodeGraph(data)
+}
+
+// Сбрасывает на диск запись каталога, чтобы переименование пережило сбой
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
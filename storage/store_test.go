@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"wintersc/graph"
+)
+
+// Шаги уплотнения, на которых может произойти сбой
+var compactionSteps = []string{"snapshot-write", "snapshot-sync", "snapshot-rename", "wal-open", "dir-sync"}
+
+var errInjected = errors.New("injected failure")
+
+// Паника, которой тест изображает падение процесса
+type crash struct{ step string }
+
+func setFailpoint(t *testing.T, fn func(step string) error) {
+	old := failpoint
+	failpoint = fn
+	t.Cleanup(func() { failpoint = old })
+}
+
+// Операции, после которых в журнале остаются записи всех типов
+var workload = []func(s *Store) error{
+	func(s *Store) error { return s.AddEdge(1, 2, 4) },
+	func(s *Store) error { return s.AddEdge(2, 3, 1) },
+	func(s *Store) error { return s.AddEdgeAt(3, 4, 7, 100) },
+	func(s *Store) error { return s.AddEdge(4, 5, 2) },
+	func(s *Store) error { return s.RemoveEdgeAt(2, 3, 200) },
+	func(s *Store) error { return s.AddVertex(9) },
+	func(s *Store) error { return s.AddEdge(1, 5, -3) },
+	func(s *Store) error { return s.RemoveEdge(1, 2) },
+}
+
+func sameGraph(a, b *graph.Graph) bool {
+	return reflect.DeepEqual(a.Edge, b.Edge) && reflect.DeepEqual(a.Adj, b.Adj)
+}
+
+func openStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s
+}
+
+// Журнал обрезается на каждом байте: восстановленный граф должен совпасть
+// с графом после всех записей, целиком попавших на диск
+func TestRecoverTornLog(t *testing.T) {
+	dir := t.TempDir()
+	store := openStore(t, dir)
+	for _, op := range workload[:3] {
+		if err := op(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	logFile := logPath(dir, 1)
+	states := []*graph.Graph{store.Graph().Clone()}
+	sizes := []int64{0}
+	for _, op := range workload[3:] {
+		if err := op(store); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, store.Graph().Clone())
+		info, err := os.Stat(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+	store.Close()
+
+	snapshot, _ := os.ReadFile(filepath.Join(dir, "snapshot"))
+	full, _ := os.ReadFile(logFile)
+	for offset := 0; offset <= len(full); offset++ {
+		crashDir := t.TempDir()
+		os.WriteFile(filepath.Join(crashDir, "snapshot"), snapshot, 0o644)
+		os.WriteFile(logPath(crashDir, 1), full[:offset], 0o644)
+
+		recovered, err := Open(crashDir)
+		if err != nil {
+			t.Fatalf("offset %d: ошибка восстановления: %v", offset, err)
+		}
+		// Число записей, целиком попавших на диск до сбоя
+		complete := 0
+		for complete+1 < len(sizes) && sizes[complete+1] <= int64(offset) {
+			complete++
+		}
+		if !sameGraph(recovered.Graph(), states[complete]) {
+			t.Errorf("offset %d: граф не совпал, ожидалось %d операций", offset, complete)
+		}
+		recovered.Close()
+	}
+}
+
+// Испорченная последняя запись — оборванный хвост, испорченная запись
+// в середине журнала — повреждение
+func TestRecoverCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	store := openStore(t, dir)
+	var sizes []int64
+	var states []*graph.Graph
+	for _, op := range workload {
+		if err := op(store); err != nil {
+			t.Fatal(err)
+		}
+		info, _ := os.Stat(logPath(dir, 0))
+		sizes = append(sizes, info.Size())
+		states = append(states, store.Graph().Clone())
+	}
+	store.Close()
+	full, _ := os.ReadFile(logPath(dir, 0))
+
+	// Портим последний байт нагрузки каждой записи
+	for i, end := range sizes {
+		crashDir := t.TempDir()
+		data := append([]byte(nil), full...)
+		data[end-1] ^= 0xff
+		os.WriteFile(logPath(crashDir, 0), data, 0o644)
+
+		recovered, err := Open(crashDir)
+		if i < len(sizes)-1 {
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("запись %d из %d: ожидалась ErrCorrupt, получено %v", i+1, len(sizes), err)
+			}
+			if err == nil {
+				recovered.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("последняя запись: ошибка восстановления: %v", err)
+		}
+		if !sameGraph(recovered.Graph(), states[i-1]) {
+			t.Errorf("последняя запись: граф не совпал с графом до неё")
+		}
+		recovered.Close()
+		// Отброшенный хвост отрезан, и журнал снова можно дописывать
+		if info, _ := os.Stat(logPath(crashDir, 0)); info.Size() != sizes[i-1] {
+			t.Errorf("длина журнала %d, ожидалось %d", info.Size(), sizes[i-1])
+		}
+	}
+}
+
+// Процесс падает на каждом шаге уплотнения: после восстановления есть все
+// подтверждённые операции, и новые операции тоже переживают перезапуск
+func TestCrashDuringCompaction(t *testing.T) {
+	for _, step := range compactionSteps {
+		t.Run(step, func(t *testing.T) {
+			dir := t.TempDir()
+			store := openStore(t, dir)
+			for _, op := range workload[:4] {
+				if err := op(store); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := store.Graph().Clone()
+
+			setFailpoint(t, func(s string) error {
+				if s == step {
+					panic(crash{s})
+				}
+				return nil
+			})
+			func() {
+				defer func() {
+					if r := recover(); r != (crash{step}) {
+						t.Fatalf("ожидался сбой на шаге %s, получено %v", step, r)
+					}
+				}()
+				store.Snapshot()
+			}()
+			failpoint = func(string) error { return nil }
+			store.Close()
+			// Если сбой оставил временный снимок, он может быть оборван на любом байте
+			tmp := filepath.Join(dir, "snapshot.tmp")
+			partial, _ := os.ReadFile(tmp)
+			for offset := 0; offset <= len(partial); offset++ {
+				crashDir := t.TempDir()
+				copyDir(t, dir, crashDir)
+				if len(partial) > 0 {
+					os.WriteFile(filepath.Join(crashDir, "snapshot.tmp"), partial[:offset], 0o644)
+				}
+				checkRecovery(t, crashDir, want)
+			}
+		})
+	}
+}
+
+// Шаг уплотнения возвращает ошибку: хранилище либо продолжает работать
+// со старым поколением, либо отказывает в записи, но подтверждённые
+// операции не теряются
+func TestCompactionFailure(t *testing.T) {
+	for i, step := range compactionSteps {
+		t.Run(step, func(t *testing.T) {
+			dir := t.TempDir()
+			store := openStore(t, dir)
+			for _, op := range workload[:4] {
+				if err := op(store); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			setFailpoint(t, func(s string) error {
+				if s == step {
+					return errInjected
+				}
+				return nil
+			})
+			if err := store.Snapshot(); !errors.Is(err, errInjected) {
+				t.Fatalf("Snapshot: ожидалась внесённая ошибка, получено %v", err)
+			}
+			afterRename := i >= 3
+			err := store.AddEdge(7, 8, 1)
+			if afterRename != errors.Is(err, ErrFailed) {
+				t.Fatalf("AddEdge после сбоя на шаге %s: %v", step, err)
+			}
+			want := store.Graph().Clone()
+			store.Close()
+			failpoint = func(string) error { return nil }
+			checkRecovery(t, dir, want)
+		})
+	}
+}
+
+// Открывает хранилище, сверяет граф и проверяет, что новая операция
+// переживает ещё один перезапуск
+func checkRecovery(t *testing.T, dir string, want *graph.Graph) {
+	t.Helper()
+	recovered := openStore(t, dir)
+	if !sameGraph(recovered.Graph(), want) {
+		t.Fatalf("граф после восстановления не совпал")
+	}
+	if err := recovered.AddEdge(10, 11, 5); err != nil {
+		t.Fatal(err)
+	}
+	want = recovered.Graph().Clone()
+	recovered.Close()
+
+	reopened := openStore(t, dir)
+	defer reopened.Close()
+	if !sameGraph(reopened.Graph(), want) {
+		t.Fatalf("операция после восстановления потерялась")
+	}
+}
+
+func copyDir(t *testing.T, from, to string) {
+	t.Helper()
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(from, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(to, e.Name()), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Автоматическое уплотнение падает на каждом шаге: операция, которая его
+// запустила, уже в журнале и подтверждается, а о сбое после переименования
+// снимка сообщает следующая запись
+func TestAutoCompactionFailure(t *testing.T) {
+	for i, step := range compactionSteps {
+		t.Run(step, func(t *testing.T) {
+			dir := t.TempDir()
+			store := openStore(t, dir)
+			store.SnapshotEvery = 3
+			setFailpoint(t, func(s string) error {
+				if s == step {
+					return errInjected
+				}
+				return nil
+			})
+			for _, op := range workload[:3] {
+				if err := op(store); err != nil {
+					t.Fatalf("операция, запустившая уплотнение: %v", err)
+				}
+			}
+			want := store.Graph().Clone()
+
+			afterRename := i >= 3
+			err := store.AddEdge(7, 8, 1)
+			if afterRename != errors.Is(err, ErrFailed) {
+				t.Fatalf("AddEdge после сбоя на шаге %s: %v", step, err)
+			}
+			if !afterRename {
+				want = store.Graph().Clone()
+			}
+			store.Close()
+			failpoint = func(string) error { return nil }
+			checkRecovery(t, dir, want)
+		})
+	}
+}
+
+// Неудачный fsync журнала: операция не применяется и не всплывает
+// при восстановлении, а журнал отказывает в следующих записях
+func TestLogSyncFailure(t *testing.T) {
+	dir := t.TempDir()
+	store := openStore(t, dir)
+	if err := store.AddEdge(1, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	want := store.Graph().Clone()
+
+	setFailpoint(t, func(s string) error {
+		if s == "wal-sync" {
+			return errInjected
+		}
+		return nil
+	})
+	if err := store.AddEdge(2, 3, 1); !errors.Is(err, errInjected) || !errors.Is(err, ErrFailed) {
+		t.Fatalf("AddEdge при сбое fsync: %v", err)
+	}
+	failpoint = func(string) error { return nil }
+	if err := store.AddEdge(3, 4, 1); !errors.Is(err, ErrFailed) {
+		t.Fatalf("AddEdge после сбоя fsync: %v", err)
+	}
+	if !sameGraph(store.Graph(), want) {
+		t.Fatal("неподтверждённая операция попала в граф")
+	}
+	store.Close()
+	checkRecovery(t, dir, want)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// Журнал упреждающей записи (WAL): файл, в который операции только дописываются
+type Log struct {
+	file   *os.File
+	size   int64 // Длина корректной части файла
+	buf    []byte
+	failed error // Ошибка fsync, после которой журналу нельзя доверять
+}
+
+// Открывает журнал, читает все целые записи и отрезает оборванный хвост,
+// оставшийся после сбоя посреди записи. Испорченная запись, за которой
+// есть другие, не отрезается: OpenLog возвращает ErrCorrupt.
+func OpenLog(path string) (*Log, []Record, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, valid, err := readRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if _, err := file.Seek(valid, 0); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return &Log{file: file, size: valid}, records, nil
+}
+
+// Дописывает записи и дожидается их сброса на диск
+func (l *Log) Append(records ...Record) error {
+	if l.failed != nil {
+		return l.failed
+	}
+	l.buf = l.buf[:0]
+	for _, r := range records {
+		l.buf = appendRecord(l.buf, r)
+	}
+	if _, err := l.file.Write(l.buf); err != nil {
+		// Убираем частично записанный хвост, чтобы следующие записи не оказались за ним
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, 0)
+		return err
+	}
+	err := failpoint("wal-sync")
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		// Записи не подтверждены, но могли остаться в файле и всплыть
+		// при восстановлении. Отрезаем их, а раз после неудачного fsync
+		// неизвестно, что из уже записанного дошло до диска, дальше
+		// журнал только возвращает ошибку.
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, 0)
+		l.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+		return l.failed
+	}
+	l.size += int64(len(l.buf))
+	return nil
+}
+
+func (l *Log) Close() error {
+	return l.file.Close()
+}